@@ -0,0 +1,224 @@
+package agents
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HeaderField is one request header, as received. Unlike http.Header, a list
+// of HeaderField keeps the order of headers, which is needed for header
+// order rules.
+type HeaderField struct {
+	Name  string
+	Value string
+}
+
+// AutomationRule is a heuristic rule of DetectAutomation that fired.
+type AutomationRule struct {
+	// Name of the rule, one of the Rule constants.
+	Name string
+
+	// Human readable details.
+	Detail string
+
+	// Weight of the rule in the score, between 0 and 1.
+	Weight float64
+}
+
+// Rules of DetectAutomation.
+const (
+	// The User-Agent or Sec-CH-UA header contains a token of an automation
+	// framework or a headless browser (e.g. "HeadlessChrome", "PhantomJS",
+	// "Selenium"), or a header set by automation tools is present.
+	RuleAutomationToken = "automation-token"
+
+	// A browser User-Agent without Accept-Language.
+	RuleMissingAcceptLanguage = "missing-accept-language"
+
+	// A browser User-Agent with a missing or generic Accept header on a
+	// document request.
+	RuleAcceptAnomaly = "accept-anomaly"
+
+	// Headers are not in the order the claimed browser sends them.
+	RuleHeaderOrder = "header-order"
+
+	// A Chrome User-Agent without Sec-Fetch-* headers.
+	RuleMissingFetchMetadata = "missing-fetch-metadata"
+
+	// A browser User-Agent with Connection: close.
+	RuleConnectionClose = "connection-close"
+)
+
+// AutomationReport is the result of DetectAutomation.
+type AutomationReport struct {
+	// Combined score between 0 and 1 of the fired rules.
+	Score float64
+
+	// Rules which fired.
+	Rules []AutomationRule
+}
+
+// Likely reports if the request most likely comes from an automated browser.
+func (r AutomationReport) Likely() bool {
+	return r.Score >= 0.5
+}
+
+func (r *AutomationReport) fire(name string, weight float64, format string, args ...interface{}) {
+	r.Rules = append(r.Rules, AutomationRule{
+		Name:   name,
+		Detail: fmt.Sprintf(format, args...),
+		Weight: weight,
+	})
+	// Combine rules as independent pieces of evidence.
+	r.Score = 1 - (1-r.Score)*(1-weight)
+}
+
+// Tokens revealing automation frameworks and headless browsers in the
+// User-Agent and Sec-CH-UA headers, in lower case.
+var automationTokens = []string{
+	"headlesschrome",
+	"phantomjs",
+	"selenium",
+	"webdriver",
+	"puppeteer",
+	"playwright",
+	"slimerjs",
+	"htmlunit",
+}
+
+// Headers set by automation tools, in lower case.
+var automationHeaders = []string{
+	"x-devtools-emulate-network-conditions-client-id",
+}
+
+// Headers scanned for automationTokens, in lower case. Other headers, e.g.
+// Referer or Cookie, carry values chosen by sites and not by the client.
+var automationTokenHeaders = map[string]bool{
+	"user-agent": true,
+	"sec-ch-ua":  true,
+}
+
+// Relative order of headers sent by browser families.
+var browserHeaderOrders = map[string][]string{
+	"Chrome":  {"user-agent", "accept", "accept-encoding", "accept-language"},
+	"Firefox": {"user-agent", "accept", "accept-language", "accept-encoding"},
+}
+
+// browserFamily returns the browser family claimed by the User-Agent, or an
+// empty string if it does not look like a browser.
+func browserFamily(userAgent string) string {
+	if !strings.HasPrefix(userAgent, "Mozilla/5.0 ") {
+		return ""
+	}
+	switch {
+	case strings.Contains(userAgent, "Firefox/"):
+		return "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		return "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		return "Safari"
+	}
+	return ""
+}
+
+// DetectAutomationHeader runs DetectAutomation on an http.Header. Since
+// http.Header does not keep the order of headers, header order rules are not
+// evaluated.
+func DetectAutomationHeader(header http.Header) AutomationReport {
+	fields := make([]HeaderField, 0, len(header))
+	for name, values := range header {
+		for _, value := range values {
+			fields = append(fields, HeaderField{Name: name, Value: value})
+		}
+	}
+	return detectAutomation(fields, false)
+}
+
+// DetectAutomation scores how likely the request headers come from a headless
+// browser or an automation framework (e.g. Puppeteer, Playwright or Selenium),
+// including ones sending a regular browser User-Agent. It looks for tokens of
+// automation tools, Accept and Accept-Language anomalies and the order of
+// headers, and reports which rules fired. Browsers driven through WebDriver
+// send the same headers as when used by a person and are not detected.
+func DetectAutomation(fields []HeaderField) AutomationReport {
+	return detectAutomation(fields, true)
+}
+
+func detectAutomation(fields []HeaderField, ordered bool) AutomationReport {
+	var report AutomationReport
+
+	values := map[string]string{}
+	positions := map[string]int{}
+	for i, field := range fields {
+		name := strings.ToLower(field.Name)
+		if _, ok := values[name]; !ok {
+			values[name] = field.Value
+			positions[name] = i
+		}
+	}
+
+	for _, field := range fields {
+		name := strings.ToLower(field.Name)
+		for _, header := range automationHeaders {
+			if name == header {
+				report.fire(RuleAutomationToken, 0.9, "header %s is set by automation tools", field.Name)
+			}
+		}
+		if !automationTokenHeaders[name] {
+			continue
+		}
+		value := strings.ToLower(field.Value)
+		for _, token := range automationTokens {
+			if strings.Contains(value, token) {
+				report.fire(RuleAutomationToken, 0.9, "header %s contains %q", field.Name, token)
+			}
+		}
+	}
+
+	family := browserFamily(values["user-agent"])
+	if family == "" {
+		// The rules below detect browsers which are not what they claim.
+		return report
+	}
+
+	if _, ok := values["accept-language"]; !ok {
+		report.fire(RuleMissingAcceptLanguage, 0.4, "%s User-Agent without Accept-Language", family)
+	}
+
+	accept, hasAccept := values["accept"]
+	isDocument := values["sec-fetch-dest"] == "document" || values["sec-fetch-mode"] == "navigate" || values["upgrade-insecure-requests"] == "1"
+	switch {
+	case !hasAccept:
+		report.fire(RuleAcceptAnomaly, 0.3, "%s User-Agent without Accept", family)
+	case isDocument && !strings.Contains(accept, "text/html"):
+		report.fire(RuleAcceptAnomaly, 0.5, "document request with Accept %q", accept)
+	}
+
+	if family == "Chrome" {
+		if _, ok := values["sec-fetch-site"]; !ok {
+			report.fire(RuleMissingFetchMetadata, 0.2, "Chrome User-Agent without Sec-Fetch-Site")
+		}
+	}
+
+	if strings.EqualFold(values["connection"], "close") {
+		report.fire(RuleConnectionClose, 0.2, "%s User-Agent with Connection: close", family)
+	}
+
+	if order, ok := browserHeaderOrders[family]; ok && ordered {
+		last, lastName := -1, ""
+		for _, name := range order {
+			pos, ok := positions[name]
+			if !ok {
+				continue
+			}
+			if pos < last {
+				report.fire(RuleHeaderOrder, 0.5, "%s sends %s before %s", family, name, lastName)
+				break
+			}
+			last, lastName = pos, name
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,140 @@
+package agents
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// readHeaderFixture reads an HTTP request and returns its headers in the order
+// they were sent.
+func readHeaderFixture(t *testing.T, path string) []HeaderField {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var fields []HeaderField
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Request line.
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			t.Fatalf("%s: malformed header line %q", path, line)
+		}
+		fields = append(fields, HeaderField{Name: name, Value: strings.TrimSpace(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return fields
+}
+
+// TestDetectAutomation runs the detector on requests of browsers and
+// automation tools in testdata/headers. A browser driven through WebDriver,
+// e.g. selenium-firefox.txt, sends the headers of the browser and is not
+// detected.
+func TestDetectAutomation(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "headers", "expected.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected map[string]struct {
+		Likely bool     `json:"likely"`
+		Rules  []string `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join("testdata", "headers", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("found %d fixtures, expected.json describes %d", len(files), len(expected))
+	}
+
+	for _, file := range files {
+		file := file
+
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			want, ok := expected[filepath.Base(file)]
+			if !ok {
+				t.Fatalf("no expectation for %s", file)
+			}
+
+			report := DetectAutomation(readHeaderFixture(t, file))
+
+			rules := []string{}
+			seen := map[string]bool{}
+			for _, rule := range report.Rules {
+				if !seen[rule.Name] {
+					seen[rule.Name] = true
+					rules = append(rules, rule.Name)
+				}
+			}
+			sort.Strings(rules)
+
+			if !reflect.DeepEqual(rules, want.Rules) {
+				t.Errorf("fired rules %v, want %v; report: %+v", rules, want.Rules, report)
+			}
+			if report.Likely() != want.Likely {
+				t.Errorf("Likely() = %v (score %.2f), want %v", report.Likely(), report.Score, want.Likely)
+			}
+			if report.Score < 0 || report.Score > 1 {
+				t.Errorf("score %v is out of range", report.Score)
+			}
+		})
+	}
+}
+
+func TestDetectAutomationHeader(t *testing.T) {
+	fields := readHeaderFixture(t, filepath.Join("testdata", "headers", "python-requests-chrome-ua.txt"))
+	header := http.Header{}
+	for _, field := range fields {
+		header.Add(field.Name, field.Value)
+	}
+
+	// Header order is unknown in http.Header.
+	report := DetectAutomationHeader(header)
+	for _, rule := range report.Rules {
+		if rule.Name == RuleHeaderOrder {
+			t.Errorf("header order rule fired on http.Header: %+v", rule)
+		}
+	}
+	if len(report.Rules) == 0 {
+		t.Errorf("no rules fired")
+	}
+}
+
+func TestDetectAutomationTokenHeaders(t *testing.T) {
+	fields := readHeaderFixture(t, filepath.Join("testdata", "headers", "chrome-120-windows.txt"))
+	fields = append(fields,
+		HeaderField{Name: "Referer", Value: "https://www.selenium.dev/documentation/webdriver/"},
+		HeaderField{Name: "Cookie", Value: "tool=puppeteer"},
+	)
+	if report := DetectAutomation(fields); len(report.Rules) != 0 {
+		t.Errorf("rules fired on tokens outside User-Agent and Sec-CH-UA: %+v", report.Rules)
+	}
+
+	for i, field := range fields {
+		if strings.EqualFold(field.Name, "User-Agent") {
+			fields[i].Value = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Slack/4.35.131 Chrome/118.0.5993.159 Electron/27.0.4 Safari/537.36"
+		}
+	}
+	if report := DetectAutomation(fields); len(report.Rules) != 0 {
+		t.Errorf("rules fired on an Electron application: %+v", report.Rules)
+	}
+}
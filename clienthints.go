@@ -0,0 +1,294 @@
+package agents
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//go:embed client-hints.json
+var clientHintsJson []byte
+
+// ClientHintBrand describes brands in Sec-CH-UA client hints revealing
+// a headless browser or an automation framework.
+type ClientHintBrand struct {
+	// Regexp of the brand.
+	Pattern string `json:"pattern"`
+
+	// Source documenting the brand.
+	URL string `json:"url"`
+
+	// Examples of brands.
+	Instances []string `json:"instances"`
+
+	// Short description of the brand.
+	Description string `json:"description,omitempty"`
+
+	// Classification tags, as in Crawler.
+	Tags []Tag `json:"tags,omitempty"`
+}
+
+// The list of brands, built from contents of client-hints.json.
+var ClientHintBrands = func() []ClientHintBrand {
+	var brands []ClientHintBrand
+	if err := json.Unmarshal(clientHintsJson, &brands); err != nil {
+		panic(err)
+	}
+	return brands
+}()
+
+var clientHintBrandRegexps = func() []*regexp.Regexp {
+	regexps := make([]*regexp.Regexp, len(ClientHintBrands))
+	for i, brand := range ClientHintBrands {
+		regexps[i] = regexp.MustCompile(brand.Pattern)
+	}
+	return regexps
+}()
+
+// Signals of Evidence.
+const (
+	// The User Agent matches a crawler.
+	SignalUserAgent = "user-agent"
+
+	// A client hints brand matches ClientHintBrands.
+	SignalBrand = "client-hint-brand"
+
+	// The User Agent claims a Chromium browser sending client hints by
+	// default, but the request has none. Client hints are only sent over
+	// HTTPS, so this is weak evidence.
+	SignalMissingHints = "missing-client-hints"
+
+	// The request has client hints, but the User Agent claims a browser
+	// which does not send them (e.g. Firefox or Safari).
+	SignalUnexpectedHints = "unexpected-client-hints"
+
+	// The Chromium version in client hints and in the User Agent differ.
+	SignalVersionMismatch = "inconsistent-version"
+
+	// The platform in client hints and in the User Agent differ.
+	SignalPlatformMismatch = "inconsistent-platform"
+
+	// The mobile flag in client hints and the User Agent differ.
+	SignalMobileMismatch = "inconsistent-mobile"
+)
+
+// Evidence is one signal that a request comes from a bot.
+type Evidence struct {
+	// What was detected, one of the Signal constants.
+	Signal string
+
+	// Human readable details.
+	Detail string
+
+	// Whether the signal alone is enough to consider the request a bot.
+	Strong bool
+}
+
+// HeaderResult is the classification of request headers.
+type HeaderResult struct {
+	// Classification of the User Agent.
+	Result
+
+	// Evidence collected from the User Agent and client hints.
+	Evidence []Evidence
+}
+
+// IsBot reports if there is any strong evidence of a bot.
+func (r HeaderResult) IsBot() bool {
+	for _, evidence := range r.Evidence {
+		if evidence.Strong {
+			return true
+		}
+	}
+	return false
+}
+
+type brandVersion struct {
+	brand   string
+	version string
+}
+
+// parseBrandList parses the structured header list of Sec-CH-UA and
+// Sec-CH-UA-Full-Version-List, e.g. `"Chromium";v="120", "Not_A Brand";v="8"`.
+// Malformed items are skipped.
+func parseBrandList(value string) []brandVersion {
+	var list []brandVersion
+	for _, item := range splitOutsideQuotes(value, ',') {
+		params := splitOutsideQuotes(item, ';')
+		brand, ok := unquoteSF(params[0])
+		if !ok {
+			continue
+		}
+		bv := brandVersion{brand: brand}
+		for _, param := range params[1:] {
+			key, val, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && key == "v" {
+				bv.version, _ = unquoteSF(val)
+			}
+		}
+		list = append(list, bv)
+	}
+	return list
+}
+
+// splitOutsideQuotes splits s by sep, ignoring separators in quoted strings.
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case inQuotes && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquoteSF unquotes a structured field string.
+func unquoteSF(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", false
+	}
+	s = s[1 : len(s)-1]
+	s = strings.ReplaceAll(s, `\"`, `"`)
+	s = strings.ReplaceAll(s, `\\`, `\`)
+	return s, true
+}
+
+var chromeVersionRegexp = regexp.MustCompile(`(?:Headless)?Chrome/(\d+)`)
+
+// uaPlatform returns the platform of the User Agent with the names used by
+// Sec-CH-UA-Platform, or an empty string if unknown.
+func uaPlatform(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "Android"):
+		return "Android"
+	case strings.Contains(userAgent, "CrOS"):
+		return "Chrome OS"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		return "iOS"
+	case strings.Contains(userAgent, "Windows"):
+		return "Windows"
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		return "macOS"
+	case strings.Contains(userAgent, "Linux"), strings.Contains(userAgent, "X11"):
+		return "Linux"
+	}
+	return ""
+}
+
+// ClassifyHeaders classifies the User-Agent header with the default Matcher
+// and evaluates client hints. See Matcher.ClassifyHeaders.
+func ClassifyHeaders(header http.Header) HeaderResult {
+	return defaultMatcher.ClassifyHeaders(header)
+}
+
+// ClassifyHeaders classifies the User-Agent header and evaluates client hints
+// (Sec-CH-UA, Sec-CH-UA-Full-Version-List, Sec-CH-UA-Platform and
+// Sec-CH-UA-Mobile), looking for brands of headless browsers and for hints
+// contradicting the User Agent.
+func (m *Matcher) ClassifyHeaders(header http.Header) HeaderResult {
+	userAgent := header.Get("User-Agent")
+	result := HeaderResult{Result: m.Classify(userAgent)}
+
+	for _, match := range result.Matches {
+		result.Evidence = append(result.Evidence, Evidence{
+			Signal: SignalUserAgent,
+			Detail: fmt.Sprintf("User-Agent matches crawler pattern %q", match.Crawler.Pattern),
+			Strong: true,
+		})
+	}
+
+	secCHUA := header.Get("Sec-CH-UA")
+	fullVersionList := header.Get("Sec-CH-UA-Full-Version-List")
+	brands := append(parseBrandList(secCHUA), parseBrandList(fullVersionList)...)
+	hasHints := secCHUA != "" || fullVersionList != ""
+
+	reported := map[string]bool{}
+	for _, bv := range brands {
+		for i, re := range clientHintBrandRegexps {
+			if re.MatchString(bv.brand) && !reported[bv.brand] {
+				reported[bv.brand] = true
+				result.Evidence = append(result.Evidence, Evidence{
+					Signal: SignalBrand,
+					Detail: fmt.Sprintf("client hints brand %q: %s", bv.brand, ClientHintBrands[i].Description),
+					Strong: true,
+				})
+			}
+		}
+	}
+
+	chromeMatch := chromeVersionRegexp.FindStringSubmatch(userAgent)
+	isChromium := chromeMatch != nil && !strings.Contains(userAgent, "Firefox/")
+	isOtherBrowser := !isChromium && strings.HasPrefix(userAgent, "Mozilla/5.0") &&
+		(strings.Contains(userAgent, "Firefox/") || strings.Contains(userAgent, "Safari/"))
+
+	if isChromium {
+		major, _ := strconv.Atoi(chromeMatch[1])
+		// Chromium sends client hints by default since version 89.
+		if !hasHints && major >= 89 {
+			result.Evidence = append(result.Evidence, Evidence{
+				Signal: SignalMissingHints,
+				Detail: fmt.Sprintf("User-Agent claims Chrome %d, but there are no client hints", major),
+			})
+		}
+
+		for _, bv := range brands {
+			if bv.brand != "Chromium" && bv.brand != "Google Chrome" {
+				continue
+			}
+			hintMajor, _, _ := strings.Cut(bv.version, ".")
+			if hintMajor != "" && hintMajor != chromeMatch[1] {
+				result.Evidence = append(result.Evidence, Evidence{
+					Signal: SignalVersionMismatch,
+					Detail: fmt.Sprintf("client hints claim %s %s, User-Agent claims Chrome %s", bv.brand, bv.version, chromeMatch[1]),
+					Strong: true,
+				})
+				break
+			}
+		}
+	}
+
+	if isOtherBrowser && hasHints {
+		result.Evidence = append(result.Evidence, Evidence{
+			Signal: SignalUnexpectedHints,
+			Detail: "User-Agent claims a browser which does not send client hints",
+			Strong: true,
+		})
+	}
+
+	if hasHints {
+		if platform, ok := unquoteSF(header.Get("Sec-CH-UA-Platform")); ok {
+			if uaPlat := uaPlatform(userAgent); uaPlat != "" && platform != "" && platform != uaPlat {
+				result.Evidence = append(result.Evidence, Evidence{
+					Signal: SignalPlatformMismatch,
+					Detail: fmt.Sprintf("client hints claim platform %q, User-Agent claims %q", platform, uaPlat),
+					Strong: true,
+				})
+			}
+		}
+
+		mobile := header.Get("Sec-CH-UA-Mobile")
+		uaMobile := strings.Contains(userAgent, "Mobile")
+		if (mobile == "?1" && !uaMobile) || (mobile == "?0" && uaMobile) {
+			result.Evidence = append(result.Evidence, Evidence{
+				Signal: SignalMobileMismatch,
+				Detail: fmt.Sprintf("client hints claim Sec-CH-UA-Mobile %s, User-Agent mobile=%v", mobile, uaMobile),
+				Strong: true,
+			})
+		}
+	}
+
+	return result
+}
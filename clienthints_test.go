@@ -0,0 +1,148 @@
+package agents
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestClientHintBrands(t *testing.T) {
+	if len(ClientHintBrands) == 0 {
+		t.Fatalf("no client hints brands")
+	}
+	for _, brand := range ClientHintBrands {
+		re := regexp.MustCompile(brand.Pattern)
+		if len(brand.Instances) == 0 {
+			t.Errorf("Brand pattern %q has no instances.", brand.Pattern)
+		}
+		if brand.URL == "" {
+			t.Errorf("Brand pattern %q has no url.", brand.Pattern)
+		}
+		for _, instance := range brand.Instances {
+			if !re.MatchString(instance) {
+				t.Errorf("Brand pattern %q misses instance %q.", brand.Pattern, instance)
+			}
+		}
+		for _, normal := range []string{"Chromium", "Google Chrome", "Microsoft Edge", "Not_A Brand", "Not(A:Brand", "Opera"} {
+			if re.MatchString(normal) {
+				t.Errorf("Brand pattern %q matches regular brand %q.", brand.Pattern, normal)
+			}
+		}
+	}
+}
+
+func TestParseBrandList(t *testing.T) {
+	got := parseBrandList(`"Chromium";v="120", "Not_A Brand";v="8", "Google Chrome";v="120.0.6099.71", "Quoted \"Brand\"";x=1, garbage`)
+	want := []brandVersion{
+		{"Chromium", "120"},
+		{"Not_A Brand", "8"},
+		{"Google Chrome", "120.0.6099.71"},
+		{`Quoted "Brand"`, ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBrandList returned %#v, want %#v", got, want)
+	}
+}
+
+func TestClassifyHeaders(t *testing.T) {
+	const (
+		chromeUA    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+		androidUA   = "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+		firefoxUA   = "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"
+		chromeHints = `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`
+	)
+
+	cases := []struct {
+		name        string
+		header      map[string]string
+		wantSignals []string
+		wantBot     bool
+	}{
+		{
+			name: "consistent Chrome",
+			header: map[string]string{
+				"User-Agent":         chromeUA,
+				"Sec-CH-UA":          chromeHints,
+				"Sec-CH-UA-Mobile":   "?0",
+				"Sec-CH-UA-Platform": `"Windows"`,
+			},
+		},
+		{
+			name: "consistent Android",
+			header: map[string]string{
+				"User-Agent":         androidUA,
+				"Sec-CH-UA":          chromeHints,
+				"Sec-CH-UA-Mobile":   "?1",
+				"Sec-CH-UA-Platform": `"Android"`,
+			},
+		},
+		{
+			name:   "Firefox without hints",
+			header: map[string]string{"User-Agent": firefoxUA},
+		},
+		{
+			name:        "crawler",
+			header:      map[string]string{"User-Agent": googlebotUA},
+			wantSignals: []string{SignalUserAgent},
+			wantBot:     true,
+		},
+		{
+			name: "headless brand",
+			header: map[string]string{
+				"User-Agent": chromeUA,
+				"Sec-CH-UA":  `"Not_A Brand";v="8", "Chromium";v="120", "HeadlessChrome";v="120"`,
+			},
+			wantSignals: []string{SignalBrand},
+			wantBot:     true,
+		},
+		{
+			name:        "Chrome without hints",
+			header:      map[string]string{"User-Agent": chromeUA},
+			wantSignals: []string{SignalMissingHints},
+		},
+		{
+			name: "Firefox with hints",
+			header: map[string]string{
+				"User-Agent": firefoxUA,
+				"Sec-CH-UA":  chromeHints,
+			},
+			wantSignals: []string{SignalUnexpectedHints},
+			wantBot:     true,
+		},
+		{
+			name: "inconsistent version and platform",
+			header: map[string]string{
+				"User-Agent":                  chromeUA,
+				"Sec-CH-UA-Full-Version-List": `"Chromium";v="119.0.6045.105", "Google Chrome";v="119.0.6045.105"`,
+				"Sec-CH-UA-Mobile":            "?1",
+				"Sec-CH-UA-Platform":          `"Linux"`,
+			},
+			wantSignals: []string{SignalVersionMismatch, SignalPlatformMismatch, SignalMobileMismatch},
+			wantBot:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tc.header {
+				header.Set(k, v)
+			}
+
+			result := ClassifyHeaders(header)
+			var signals []string
+			for _, evidence := range result.Evidence {
+				signals = append(signals, evidence.Signal)
+			}
+			if !reflect.DeepEqual(signals, tc.wantSignals) {
+				t.Errorf("got signals %v, want %v; evidence: %+v", signals, tc.wantSignals, result.Evidence)
+			}
+			if result.IsBot() != tc.wantBot {
+				t.Errorf("IsBot() = %v, want %v", result.IsBot(), tc.wantBot)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+// Fields added by --annotate.
+const (
+	fieldIsBot    = "is_bot"
+	fieldCrawler  = "crawler"
+	fieldTags     = "crawler_tags"
+	fieldOperator = "crawler_operator"
+	fieldVersion  = "crawler_version"
+)
+
+var annotationFields = []string{fieldIsBot, fieldCrawler, fieldTags, fieldOperator, fieldVersion}
+
+const defaultAnnotationFields = "is_bot,crawler,crawler_tags,crawler_operator"
+
+// Formats of the fields appended to text lines.
+const (
+	// Space separated key=value pairs, with quoted strings.
+	formatKV = "kv"
+
+	// Tab separated values, "-" for missing ones.
+	formatTSV = "tsv"
+)
+
+// annotator adds the classification of the User Agent to a log line. Fields
+// are appended to text lines in its format and inserted into JSON objects.
+type annotator struct {
+	format string
+	fields []string
+}
+
+func newAnnotator(format, fields string) (*annotator, error) {
+	if format != formatKV && format != formatTSV {
+		return nil, fmt.Errorf("unknown annotation format %q (accepted: %s, %s)", format, formatKV, formatTSV)
+	}
+	a := &annotator{format: format}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if !isAnnotationField(field) {
+			return nil, fmt.Errorf("unknown annotation field %q (accepted: %s)", field, strings.Join(annotationFields, ", "))
+		}
+		a.fields = append(a.fields, field)
+	}
+	return a, nil
+}
+
+func isAnnotationField(field string) bool {
+	for _, f := range annotationFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// annotation is the classification of a line. The crawler, operator and
+// version are those of the first match, and tags are of all matches.
+type annotation struct {
+	isBot    bool
+	crawler  string
+	tags     agents.TagSet
+	operator string
+	version  string
+}
+
+func newAnnotation(result agents.Result) annotation {
+	a := annotation{isBot: result.IsCrawler()}
+	if !a.isBot {
+		return a
+	}
+	a.crawler = result.Matches[0].Crawler.Pattern
+	a.version = result.Matches[0].Version
+	if operators := result.Operators(); len(operators) != 0 {
+		a.operator = operators[0]
+	}
+	for _, match := range result.Matches {
+		a.tags |= agents.NewTagSet(match.Crawler.Tags...)
+	}
+	return a
+}
+
+// value returns the value of the field, as a string or a TagSet, or nil if
+// missing.
+func (a annotation) value(field string) interface{} {
+	var s string
+	switch field {
+	case fieldIsBot:
+		return a.isBot
+	case fieldTags:
+		if a.tags == 0 {
+			return nil
+		}
+		return a.tags
+	case fieldCrawler:
+		s = a.crawler
+	case fieldOperator:
+		s = a.operator
+	case fieldVersion:
+		s = a.version
+	}
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// annotate returns the line with the fields of the classification result.
+func (a *annotator) annotate(line string, result agents.Result, isJSON bool) string {
+	an := newAnnotation(result)
+	if isJSON {
+		return a.insertJSON(line, an)
+	}
+
+	var b strings.Builder
+	b.WriteString(line)
+	for _, field := range a.fields {
+		value := an.value(field)
+		switch a.format {
+		case formatTSV:
+			b.WriteByte('\t')
+			if value == nil {
+				b.WriteByte('-')
+			} else {
+				fmt.Fprint(&b, value)
+			}
+		default:
+			b.WriteByte(' ')
+			b.WriteString(field)
+			b.WriteByte('=')
+			switch value := value.(type) {
+			case bool:
+				b.WriteString(strconv.FormatBool(value))
+			case nil:
+				b.WriteString(`""`)
+			default:
+				b.WriteString(strconv.Quote(fmt.Sprint(value)))
+			}
+		}
+	}
+	return b.String()
+}
+
+// insertJSON adds the fields to the end of the JSON object of the line,
+// keeping the line as is otherwise. Fields which the object already has are
+// replaced, since duplicate keys are read inconsistently by JSON decoders.
+func (a *annotator) insertJSON(line string, an annotation) string {
+	var b strings.Builder
+	trimmed := strings.TrimRight(line, " \t\r")
+	body := a.removeJSONFields(strings.TrimSuffix(trimmed, "}"))
+	b.WriteString(body)
+
+	sep := ","
+	if strings.TrimSpace(body) == "{" {
+		sep = ""
+	}
+	for _, field := range a.fields {
+		value := an.value(field)
+		if tags, ok := value.(agents.TagSet); ok {
+			value = tags.Tags()
+		}
+		encoded, _ := json.Marshal(value)
+		b.WriteString(sep)
+		b.WriteString(strconv.Quote(field))
+		b.WriteByte(':')
+		b.Write(encoded)
+		sep = ","
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// removeJSONFields removes the members named as the fields from body, a JSON
+// object without its closing brace. Each member is removed with the comma
+// before it, and the comma of the first kept member if the first members
+// are removed.
+func (a *annotator) removeJSONFields(body string) string {
+	fields := make(map[string]bool, len(a.fields))
+	for _, field := range a.fields {
+		fields[field] = true
+	}
+
+	dec := json.NewDecoder(strings.NewReader(body + "}"))
+	if _, err := dec.Token(); err != nil {
+		return body
+	}
+	var b strings.Builder
+	start := int(dec.InputOffset())
+	b.WriteString(body[:start])
+	removed := false
+	for dec.More() {
+		memberStart := int(dec.InputOffset())
+		key, err := dec.Token()
+		if err != nil {
+			return body
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return body
+		}
+		memberEnd := int(dec.InputOffset())
+		if name, ok := key.(string); ok && fields[name] {
+			removed = true
+			continue
+		}
+		b.WriteString(body[memberStart:memberEnd])
+	}
+	if !removed {
+		return body
+	}
+	b.WriteString(body[int(dec.InputOffset()):])
+
+	// The first kept member starts with the comma which followed a removed
+	// member.
+	kept := b.String()
+	rest := strings.TrimLeft(kept[start:], " \t\r\n")
+	if strings.HasPrefix(rest, ",") {
+		kept = kept[:start] + rest[1:]
+	}
+	return kept
+}
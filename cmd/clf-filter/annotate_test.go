@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+const (
+	googlebotLine = `1.2.3.4 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"`
+	browserLine   = `1.2.3.4 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"`
+)
+
+func TestAnnotate(t *testing.T) {
+	cases := []struct {
+		name   string
+		format string
+		fields string
+		line   string
+		want   string
+	}{
+		{
+			name:   "kv bot",
+			format: formatKV,
+			fields: defaultAnnotationFields + ",crawler_version",
+			line:   googlebotLine,
+			want:   googlebotLine + ` is_bot=true crawler="Googlebot\\/" crawler_tags="search-engine" crawler_operator="google" crawler_version="2.1"`,
+		},
+		{
+			name:   "kv browser",
+			format: formatKV,
+			fields: defaultAnnotationFields,
+			line:   browserLine,
+			want:   browserLine + ` is_bot=false crawler="" crawler_tags="" crawler_operator=""`,
+		},
+		{
+			name:   "tsv bot",
+			format: formatTSV,
+			fields: "crawler_operator,is_bot",
+			line:   googlebotLine,
+			want:   googlebotLine + "\tgoogle\ttrue",
+		},
+		{
+			name:   "tsv browser",
+			format: formatTSV,
+			fields: defaultAnnotationFields,
+			line:   browserLine,
+			want:   browserLine + "\tfalse\t-\t-\t-",
+		},
+		{
+			name:   "json",
+			format: formatTSV,
+			fields: defaultAnnotationFields,
+			line:   `{"status":200,"http_user_agent":"curl/8.4.0"} `,
+			want:   `{"status":200,"http_user_agent":"curl/8.4.0","is_bot":true,"crawler":"^curl","crawler_tags":["http-library"],"crawler_operator":null}`,
+		},
+		{
+			name:   "json without user agent",
+			format: formatKV,
+			fields: "is_bot,crawler",
+			line:   `{ }`,
+			want:   `{ "is_bot":false,"crawler":null}`,
+		},
+		{
+			name:   "json with annotation fields",
+			format: formatKV,
+			fields: "is_bot,crawler",
+			line:   `{"is_bot":"maybe","http_user_agent":"curl/8.4.0", "crawler" : {"a":[1,"}"]},"status":200}`,
+			want:   `{"http_user_agent":"curl/8.4.0","status":200,"is_bot":true,"crawler":"^curl"}`,
+		},
+		{
+			name:   "json with annotation fields only",
+			format: formatKV,
+			fields: "is_bot,crawler",
+			line:   `{"crawler":"old", "is_bot":false }`,
+			want:   `{"is_bot":false,"crawler":null}`,
+		},
+		{
+			name:   "invalid json as text",
+			format: formatKV,
+			fields: "is_bot",
+			line:   `{"http_user_agent":"curl/8.4.0"`,
+			want:   `{"http_user_agent":"curl/8.4.0" is_bot=true`,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := newAnnotator(tc.format, tc.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := &filter{matcher: agents.DefaultMatcher(), uaField: "http_user_agent", annotate: a}
+			var c counts
+			got, ok, err := f.process(tc.line, false, &c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatalf("line is not kept")
+			}
+			if got != tc.want {
+				t.Errorf("got  %s\nwant %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewAnnotator(t *testing.T) {
+	if _, err := newAnnotator("csv", defaultAnnotationFields); err == nil {
+		t.Error("unknown format is accepted")
+	}
+	if _, err := newAnnotator(formatKV, "is_bot,user_agent"); err == nil {
+		t.Error("unknown field is accepted")
+	}
+}
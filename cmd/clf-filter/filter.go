@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	agents "github.com/monperrus/crawler-user-agents"
+	"github.com/monperrus/crawler-user-agents/internal/accesslog"
+)
+
+// counts are the numbers of lines of one input.
+type counts struct {
+	lines int
+	bots  int
+	kept  int
+
+	// Lines longer than the maximum length, and lines with invalid UTF-8 or
+	// NUL bytes.
+	long      int
+	malformed int
+}
+
+func (c *counts) add(other counts) {
+	c.lines += other.lines
+	c.bots += other.bots
+	c.kept += other.kept
+	c.long += other.long
+	c.malformed += other.malformed
+}
+
+// summary describes the counts, e.g. "10 lines, 2 bots, 8 kept".
+func (c counts) summary(longAction string) string {
+	s := fmt.Sprintf("%d lines, %d bots, %d kept", c.lines, c.bots, c.kept)
+	if c.long != 0 {
+		s += fmt.Sprintf(", %d too long %s", c.long, longAction)
+	}
+	if c.malformed != 0 {
+		s += fmt.Sprintf(", %d malformed", c.malformed)
+	}
+	return s
+}
+
+// filter classifies log lines and selects the ones to write.
+type filter struct {
+	matcher *agents.Matcher
+	metrics *agents.Metrics
+	botOnly bool
+
+	// selection selects bot lines by the matching crawlers, and invert keeps
+	// the lines which are not selected.
+	selection selection
+	invert    bool
+
+	// uaField is the field of the User Agent in JSON lines.
+	uaField string
+
+	// annotate, if not nil, keeps all lines and adds their classification.
+	annotate *annotator
+
+	// maxLine is the length from which lines are too long, 0 for
+	// accesslog.DefaultMaxLineLength. Long lines are skipped unless truncateLong is set.
+	maxLine      int
+	truncateLong bool
+
+	// strict makes long and malformed lines an error.
+	strict bool
+}
+
+// userAgent extracts the User Agent of the line, which is either in the
+// Combined Log Format or a JSON object, and reports if it is a JSON object.
+// Invalid UTF-8 and NUL bytes of the User Agent are sanitized.
+func (f *filter) userAgent(line string) (ua string, ok, isJSON bool) {
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			err := json.Unmarshal(fields[f.uaField], &ua)
+			return sanitize(ua), err == nil, true
+		}
+	}
+	ua, ok = accesslog.UserAgent(line)
+	return sanitize(ua), ok, false
+}
+
+// classify classifies the User Agent, counting it in the metrics.
+func (f *filter) classify(ua string) agents.Result {
+	if f.metrics != nil {
+		return f.metrics.Classify(f.matcher, ua)
+	}
+	return f.matcher.Classify(ua)
+}
+
+// match classifies the User Agent of the line and reports if it is a bot and
+// if it is selected.
+func (f *filter) match(line string) (isBot, selected bool) {
+	ua, ok, _ := f.userAgent(line)
+	switch {
+	case !ok:
+		return false, false
+	case f.metrics == nil && f.selection.empty():
+		isBot = f.matcher.IsCrawler(ua)
+		return isBot, isBot
+	}
+
+	var indices []int
+	if f.metrics != nil {
+		for _, match := range f.metrics.Classify(f.matcher, ua).Matches {
+			indices = append(indices, match.Index)
+		}
+	} else {
+		indices = f.matcher.MatchingCrawlers(ua)
+	}
+	return len(indices) != 0, f.selection.selects(f.matcher, indices)
+}
+
+// splitter returns a LineSplitter with the maximum line length.
+func (f *filter) splitter() *accesslog.LineSplitter {
+	max := f.maxLine
+	if max <= 0 {
+		max = accesslog.DefaultMaxLineLength
+	}
+	return &accesslog.LineSplitter{Max: max}
+}
+
+// run filters the lines of r into out.
+func (f *filter) run(r io.Reader, out *output) (counts, error) {
+	var c counts
+	buf := out.buffer()
+	defer buf.flush()
+
+	lines := f.splitter()
+	emit := func(data []byte, long bool) error {
+		line, ok, err := f.process(string(data), long, &c)
+		if ok {
+			buf.writeLine(line)
+		}
+		return err
+	}
+
+	err := lines.ReadLines(r, emit)
+	return c, err
+}
+
+// process classifies the line and counts it. It returns the line to write,
+// annotated if configured, and if it is to be written. Long lines, cut by
+// a LineSplitter, are skipped unless truncateLong is set. In strict mode, long
+// and malformed lines are an error.
+func (f *filter) process(line string, long bool, c *counts) (string, bool, error) {
+	c.lines++
+	if long {
+		c.long++
+		if f.strict {
+			return "", false, fmt.Errorf("line %d: longer than %d bytes", c.lines, len(line))
+		}
+		if !f.truncateLong {
+			return "", false, nil
+		}
+	}
+	if malformed(line) {
+		c.malformed++
+		if f.strict {
+			return "", false, fmt.Errorf("line %d: invalid UTF-8 or NUL byte", c.lines)
+		}
+	}
+
+	if f.annotate != nil {
+		ua, ok, isJSON := f.userAgent(line)
+		var result agents.Result
+		if ok {
+			result = f.classify(ua)
+		}
+		if result.IsCrawler() {
+			c.bots++
+		}
+		c.kept++
+		return f.annotate.annotate(line, result, isJSON), true, nil
+	}
+
+	isBot, selected := f.match(line)
+	if isBot {
+		c.bots++
+	}
+	// Without a selection, all bots are selected. Selected bots are kept with
+	// -bot and removed otherwise, keeping other lines.
+	keep := selected
+	if !f.botOnly {
+		keep = !selected
+	}
+	if keep == f.invert {
+		return "", false, nil
+	}
+	c.kept++
+	return line, true, nil
+}
+
+// output is shared by the workers filtering inputs in parallel. Lines are
+// written whole, but lines of different inputs may be interleaved.
+type output struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+func newOutput(w io.Writer) *output {
+	return &output{w: bufio.NewWriter(w)}
+}
+
+func (o *output) write(p []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.w.Write(p)
+}
+
+// flush writes buffered data to the underlying writer.
+func (o *output) flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.w.Flush()
+}
+
+// buffer returns a lineBuffer of one worker.
+func (o *output) buffer() *lineBuffer {
+	return &lineBuffer{out: o}
+}
+
+// lineBufferSize is the size from which a lineBuffer writes its lines.
+const lineBufferSize = 64 * 1024
+
+// lineBuffer collects lines of one worker and writes them to the output in
+// chunks of whole lines.
+type lineBuffer struct {
+	out *output
+	buf []byte
+}
+
+func (b *lineBuffer) writeLine(line string) {
+	b.buf = append(b.buf, line...)
+	b.buf = append(b.buf, '\n')
+	if len(b.buf) >= lineBufferSize {
+		b.flush()
+	}
+}
+
+func (b *lineBuffer) flush() {
+	if len(b.buf) != 0 {
+		b.out.write(b.buf)
+		b.buf = b.buf[:0]
+	}
+}
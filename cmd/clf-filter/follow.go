@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/monperrus/crawler-user-agents/internal/accesslog"
+)
+
+// followInterval is the interval between checks of a followed file for new
+// lines and rotation.
+const followInterval = 250 * time.Millisecond
+
+// follower reads the lines appended to a file, like tail -F. It detects that
+// the file was rotated when its path refers to another file, e.g. after
+// logrotate renamed it and created a new one, and that it was truncated when
+// it gets smaller than what was read, e.g. with copytruncate.
+type follower struct {
+	path string
+
+	// poll is the interval between checks for new lines and rotation.
+	poll time.Duration
+
+	// hup triggers a reopening of the path, e.g. on SIGHUP.
+	hup <-chan os.Signal
+
+	file   *os.File
+	info   os.FileInfo
+	offset int64
+	lines  *accesslog.LineSplitter
+	buf    []byte
+}
+
+// newFollower opens the file at its end, so that only lines appended from now
+// on are followed.
+func newFollower(path string, poll time.Duration, hup <-chan os.Signal) (*follower, error) {
+	fl := &follower{
+		path:  path,
+		poll:  poll,
+		hup:   hup,
+		lines: &accesslog.LineSplitter{Max: accesslog.DefaultMaxLineLength},
+		buf:   make([]byte, 64*1024),
+	}
+	f, info, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(info.Size(), io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	fl.file, fl.info, fl.offset = f, info, info.Size()
+	return fl, nil
+}
+
+func openFile(path string) (*os.File, os.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+func (fl *follower) close() error {
+	return fl.file.Close()
+}
+
+// follow calls emit with each line appended to the file until ctx is done or
+// emit returns an error, as LineSplitter.Write does. A last line without
+// a newline is emitted when the file is rotated or following stops.
+func (fl *follower) follow(ctx context.Context, emit func(line []byte, long bool) error) error {
+	ticker := time.NewTicker(fl.poll)
+	defer ticker.Stop()
+
+	for {
+		if err := fl.read(emit); err != nil {
+			return err
+		}
+		changed, err := fl.check(emit)
+		if err != nil {
+			return err
+		}
+		if changed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return fl.flushPartial(emit)
+		case <-fl.hup:
+			if err := fl.reopen(emit); err != nil {
+				return err
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// read emits the complete lines read up to the end of the file.
+func (fl *follower) read(emit func(line []byte, long bool) error) error {
+	for {
+		n, err := fl.file.Read(fl.buf)
+		if n > 0 {
+			fl.offset += int64(n)
+			if err := fl.lines.Write(fl.buf[:n], emit); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || (err == nil && n == 0) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (fl *follower) flushPartial(emit func(line []byte, long bool) error) error {
+	if fl.lines.Pending() {
+		return fl.lines.Flush(emit)
+	}
+	return nil
+}
+
+// check detects rotation and truncation of the file, and reports if it now
+// reads another file or from the start.
+func (fl *follower) check(emit func(line []byte, long bool) error) (bool, error) {
+	info, err := os.Stat(fl.path)
+	if os.IsNotExist(err) {
+		// Renamed, and the new file is not created yet.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(info, fl.info) {
+		return true, fl.reopen(emit)
+	}
+	if info.Size() < fl.offset {
+		if err := fl.flushPartial(emit); err != nil {
+			return false, err
+		}
+		if _, err := fl.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		fl.offset = 0
+		return true, nil
+	}
+	return false, nil
+}
+
+// reopen reads the rest of the file and opens the path again. If it is still
+// the same file, reading continues where it stopped, otherwise it starts at
+// the beginning of the new file. A missing path is retried at the next check.
+func (fl *follower) reopen(emit func(line []byte, long bool) error) error {
+	if err := fl.read(emit); err != nil {
+		return err
+	}
+	f, info, err := openFile(fl.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	offset := int64(0)
+	if os.SameFile(info, fl.info) && info.Size() >= fl.offset {
+		offset = fl.offset
+	} else if err := fl.flushPartial(emit); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	fl.file.Close()
+	fl.file, fl.info, fl.offset = f, info, offset
+	return nil
+}
+
+// followInput filters the lines appended to the file until ctx is done,
+// writing each kept line as soon as it is read.
+func (f *filter) followInput(ctx context.Context, name string, hup <-chan os.Signal, out *output) result {
+	res := result{name: name}
+
+	fl, err := newFollower(name, followInterval, hup)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	defer fl.close()
+	fl.lines = f.splitter()
+
+	res.err = fl.follow(ctx, func(data []byte, long bool) error {
+		line, ok, err := f.process(string(data), long, &res.counts)
+		if ok {
+			out.write(append([]byte(line), '\n'))
+			out.flush()
+		}
+		return err
+	})
+	if res.err != nil {
+		res.err = fmt.Errorf("%s: %w", name, res.err)
+	}
+	return res
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startFollow follows the file in the background, sending its lines to the
+// returned channel.
+func startFollow(t *testing.T, path string, poll time.Duration, hup <-chan os.Signal) <-chan string {
+	t.Helper()
+	fl, err := newFollower(path, poll, hup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- fl.follow(ctx, func(line []byte, long bool) error {
+			lines <- string(line)
+			return nil
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("follow: %v", err)
+		}
+		fl.close()
+	})
+	return lines
+}
+
+func expectLines(t *testing.T, lines <-chan string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-lines:
+			if got != w {
+				t.Fatalf("got line %q, want %q", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for line %q", w)
+		}
+	}
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollowAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	appendFile(t, path, "existing line\n")
+
+	lines := startFollow(t, path, 10*time.Millisecond, nil)
+	appendFile(t, path, "first\r\nsec")
+	expectLines(t, lines, "first")
+	appendFile(t, path, "ond\n")
+	expectLines(t, lines, "second")
+}
+
+func TestFollowRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	rotated := filepath.Join(dir, "access.log.1")
+	appendFile(t, path, "")
+
+	lines := startFollow(t, path, 10*time.Millisecond, nil)
+	appendFile(t, path, "before rotation\n")
+	expectLines(t, lines, "before rotation")
+
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatal(err)
+	}
+	// Written by the server before it reopens its log.
+	appendFile(t, rotated, "late line\nno newline")
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, path, "after rotation\n")
+
+	expectLines(t, lines, "late line", "no newline", "after rotation")
+}
+
+func TestFollowTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	appendFile(t, path, "")
+
+	lines := startFollow(t, path, 10*time.Millisecond, nil)
+	appendFile(t, path, "a long line before truncation\n")
+	expectLines(t, lines, "a long line before truncation")
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "short\n")
+	expectLines(t, lines, "short")
+}
+
+func TestFollowHUP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	appendFile(t, path, "")
+
+	// Only SIGHUP makes the follower check the file.
+	hup := make(chan os.Signal, 1)
+	lines := startFollow(t, path, time.Hour, hup)
+
+	appendFile(t, path, "old file\n")
+	if err := os.Rename(path, filepath.Join(dir, "access.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "new file\n")
+	hup <- syscall.SIGHUP
+	expectLines(t, lines, "old file", "new file")
+
+	appendFile(t, path, "same file\n")
+	hup <- syscall.SIGHUP
+	expectLines(t, lines, "same file")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// expandArgs expands glob patterns in the arguments. Arguments without glob
+// characters are kept as is, so that missing files are reported when opened.
+// Each glob is sorted, e.g. access.log.1.gz, access.log.2.gz.
+func expandArgs(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", arg, err)
+		}
+		if matches == nil {
+			if hasMeta(arg) {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+			matches = []string{arg}
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func hasMeta(path string) bool {
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
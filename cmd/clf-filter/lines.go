@@ -0,0 +1,21 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// malformed reports if the line is not valid UTF-8 or contains a NUL byte.
+func malformed(line string) bool {
+	return !utf8.ValidString(line) || strings.IndexByte(line, 0) >= 0
+}
+
+// sanitize replaces invalid UTF-8 sequences and NUL bytes with U+FFFD, so that
+// malformed User Agents are matched the same way by literals and regular
+// expressions.
+func sanitize(s string) string {
+	if !malformed(s) {
+		return s
+	}
+	return strings.ReplaceAll(strings.ToValidUTF8(s, "�"), "\x00", "�")
+}
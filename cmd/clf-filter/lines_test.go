@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+func TestSanitize(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"Googlebot/2.1", "Googlebot/2.1"},
+		{"Google\x00bot", "Google\ufffdbot"},
+		{"Googlebot\xff\xfe/2.1", "Googlebot\ufffd/2.1"},
+	}
+	for _, tc := range cases {
+		if got := sanitize(tc.in); got != tc.want {
+			t.Errorf("sanitize(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestLongAndMalformedLines(t *testing.T) {
+	long := `1.2.3.4 - - [10/Oct/2023:13:55:36 +0000] "GET /` + strings.Repeat("a", 200) + ` HTTP/1.1" 200 2326 "-" "curl/8.4.0"`
+	nul := `1.2.3.4 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "Mozilla/5.0 (X11; Linux x86_64)` + "\x00" + `"`
+	invalid := `1.2.3.4 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "Mozilla/5.0 (compatible; Googlebot/2.1; ` + "\xff" + `)"`
+	input := strings.Join([]string{googlebotLine, long, nul, browserLine, invalid}, "\n")
+
+	cases := []struct {
+		name         string
+		truncateLong bool
+		strict       bool
+		want         counts
+		wantLines    int
+		wantErr      string
+	}{
+		{name: "skip", want: counts{lines: 5, bots: 2, kept: 2, long: 1, malformed: 2}, wantLines: 2},
+		{name: "truncate", truncateLong: true, want: counts{lines: 5, bots: 2, kept: 3, long: 1, malformed: 2}, wantLines: 3},
+		{name: "strict", strict: true, want: counts{lines: 2, bots: 1, kept: 0, long: 1}, wantErr: "line 2: longer than 150 bytes"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			f := &filter{
+				matcher:      agents.DefaultMatcher(),
+				maxLine:      150,
+				truncateLong: tc.truncateLong,
+				strict:       tc.strict,
+			}
+			var buf bytes.Buffer
+			out := newOutput(&buf)
+			c, err := f.run(strings.NewReader(input), out)
+			out.flush()
+
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if c != tc.want {
+				t.Errorf("got counts %+v, want %+v", c, tc.want)
+			}
+			if lines := strings.Count(buf.String(), "\n"); tc.wantErr == "" && lines != tc.wantLines {
+				t.Errorf("got %d lines, want %d:\n%s", lines, tc.wantLines, buf.String())
+			}
+		})
+	}
+}
@@ -1,51 +1,253 @@
-// clf-filter reads Combined Log Format lines from stdin and writes them to stdout,
-// removing bot/crawler lines by default. Use --bot to keep only bot lines.
+// clf-filter reads Combined Log Format lines from files, or stdin, and writes
+// them to stdout, removing bot/crawler lines by default. Use --bot to keep only
+// bot lines. Files may be given as globs and may be compressed with gzip or
+// bzip2, which is detected from their contents; use --parallel to filter
+// several files at once. Line counts of each file are reported on stderr.
+// Use --metrics to write classification counters in the Prometheus text format
+// to a file, e.g. for the textfile collector of node_exporter. Use --normalize
+// to repair mangled User Agents (URL-encoded, escaped, quoted) before matching.
+// Lines longer than --max-line-length are skipped, or cut with --long-lines
+// truncate, and invalid UTF-8 and NUL bytes of User Agents are replaced before
+// matching; both are counted in the summary, and --strict fails on them.
+// Use --include-tag, --exclude-tag and --pattern to remove only the lines of
+// some bots, e.g. --include-tag ai-crawler, or with --bot to keep only them;
+// --invert keeps the removed lines and removes the others.
+// Use --follow to filter the lines appended to one file until interrupted,
+// following it across rotations; the file is reopened on SIGHUP.
+//
+// Use --annotate to keep all lines and add their classification instead: the
+// fields are appended to text lines, as key=value pairs or tab separated
+// values (--annotate-format), and inserted into lines of JSON logs, whose User
+// Agent is read from the --json-ua-field field.
 package main
 
 import (
-	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 
 	agents "github.com/monperrus/crawler-user-agents"
+	"github.com/monperrus/crawler-user-agents/internal/accesslog"
 )
 
-func extractUserAgent(line string) (string, bool) {
-	// Combined Log Format ends with: "referer" "user-agent"
-	// Find the last quoted field.
-	end := strings.LastIndex(line, "\"")
-	if end < 1 {
-		return "", false
+// writeMetrics writes the metrics to the file. It writes to a temporary file
+// first, so that a collector never reads a partial file.
+func writeMetrics(metrics *agents.Metrics, path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
 	}
-	start := strings.LastIndex(line[:end], "\"")
-	if start < 0 {
-		return "", false
+	if _, err := metrics.WriteTo(f); err != nil {
+		f.Close()
+		return err
 	}
-	return line[start+1 : end], true
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// result of filtering one input.
+type result struct {
+	name   string
+	counts counts
+	err    error
+}
+
+// filterInput filters one file, or stdin if name is "-".
+func (f *filter) filterInput(name string, out *output) result {
+	res := result{name: name}
+
+	var r io.Reader
+	if name == "-" {
+		r, res.err = accesslog.Decompress(os.Stdin)
+	} else {
+		var closer io.Closer
+		r, closer, res.err = accesslog.Open(name)
+		if closer != nil {
+			defer closer.Close()
+		}
+	}
+	if res.err != nil {
+		return res
+	}
+
+	res.counts, res.err = f.run(r, out)
+	if res.err != nil {
+		res.err = fmt.Errorf("%s: %w", name, res.err)
+	}
+	return res
+}
+
+// filterInputs filters the inputs with the given number of workers. Results
+// are in the order of inputs.
+func (f *filter) filterInputs(names []string, parallel int, out *output) []result {
+	results := make([]result, len(names))
+	if parallel < 2 {
+		for i, name := range names {
+			results[i] = f.filterInput(name, out)
+		}
+		return results
+	}
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = f.filterInput(names[i], out)
+			}
+		}()
+	}
+	for i := range names {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return results
 }
 
 func main() {
 	botOnly := flag.Bool("bot", false, "keep only bot/crawler lines (default: remove bots)")
+	metricsFile := flag.String("metrics", "", "write classification metrics in Prometheus text format to this file")
+	maxPatternLabels := flag.Int("max-pattern-labels", 200, "maximum number of distinct crawler patterns in metrics")
+	normalize := flag.Bool("normalize", false, "repair mangled User Agents (URL-encoded, escaped, quoted) before matching")
+	parallel := flag.Int("parallel", 1, "number of files to filter in parallel; lines of different files may then be interleaved")
+	includeTags := flag.String("include-tag", "", "select bot lines matching a crawler with one of these comma-separated tags")
+	excludeTags := flag.String("exclude-tag", "", "select bot lines not matching a crawler with one of these comma-separated tags")
+	pattern := flag.String("pattern", "", "select bot lines matching a crawler whose pattern contains this string")
+	invert := flag.Bool("invert", false, "keep the lines which would be removed and remove the others")
+	annotate := flag.Bool("annotate", false, "keep all lines and add their classification")
+	annotateFormat := flag.String("annotate-format", formatKV, "format of the fields appended to text lines: kv or tsv")
+	annotateFields := flag.String("annotate-fields", defaultAnnotationFields, "comma-separated fields to add, among "+strings.Join(annotationFields, ", "))
+	uaField := flag.String("json-ua-field", "http_user_agent", "field of the User Agent in JSON log lines")
+	maxLine := flag.Int("max-line-length", accesslog.DefaultMaxLineLength, "length in bytes from which lines are too long")
+	longLines := flag.String("long-lines", "skip", "what to do with too long lines: skip or truncate")
+	strict := flag.Bool("strict", false, "fail on too long lines and lines with invalid UTF-8 or NUL bytes")
+	follow := flag.Bool("follow", false, "filter lines appended to one file until interrupted, across rotations, writing each line at once")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: clf-filter [flags] [file or glob ...]\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	scanner := bufio.NewScanner(os.Stdin)
-	// Support long lines (e.g. large URLs).
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	names, err := expandArgs(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "clf-filter:", err)
+		os.Exit(2)
+	}
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	if *follow && (len(names) != 1 || names[0] == "-") {
+		fmt.Fprintln(os.Stderr, "clf-filter: -follow needs exactly one file")
+		os.Exit(2)
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		ua, ok := extractUserAgent(line)
-		isBot := ok && agents.IsCrawler(ua)
+	f := &filter{
+		matcher: agents.DefaultMatcher(),
+		botOnly: *botOnly,
+		uaField: *uaField,
+		invert:  *invert,
+		maxLine: *maxLine,
+		strict:  *strict,
+	}
+	switch *longLines {
+	case "skip":
+	case "truncate":
+		f.truncateLong = true
+	default:
+		fmt.Fprintf(os.Stderr, "clf-filter: unknown -long-lines %q (accepted: skip, truncate)\n", *longLines)
+		os.Exit(2)
+	}
+	if *maxLine < 1 {
+		fmt.Fprintln(os.Stderr, "clf-filter: -max-line-length must be at least 1")
+		os.Exit(2)
+	}
+	f.selection.pattern = *pattern
+	if f.selection.include, err = parseTags(*includeTags); err == nil {
+		f.selection.exclude, err = parseTags(*excludeTags)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "clf-filter:", err)
+		os.Exit(2)
+	}
+	if *annotate {
+		if *botOnly || *invert || !f.selection.empty() {
+			fmt.Fprintln(os.Stderr, "clf-filter: -annotate keeps all lines and cannot be used with -bot, -invert or a selection")
+			os.Exit(2)
+		}
+		f.annotate, err = newAnnotator(*annotateFormat, *annotateFields)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "clf-filter:", err)
+			os.Exit(2)
+		}
+	}
+	if *metricsFile != "" {
+		f.metrics = agents.NewMetrics(*maxPatternLabels)
+	}
+	if *normalize {
+		f.matcher, err = agents.NewMatcherWithOptions(agents.Crawlers, agents.MatcherOptions{Normalize: agents.NormalizeUserAgent})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "clf-filter:", err)
+			os.Exit(1)
+		}
+	}
+
+	out := newOutput(os.Stdout)
+	var results []result
+	if *follow {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		results = []result{f.followInput(ctx, names[0], hup, out)}
+		signal.Stop(hup)
+		stop()
+	} else {
+		results = f.filterInputs(names, *parallel, out)
+	}
+	if err := out.flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "clf-filter: write error:", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	var total counts
+	longAction := "skipped"
+	if f.truncateLong {
+		longAction = "truncated"
+	}
+	for _, res := range results {
+		if res.err != nil {
+			fmt.Fprintln(os.Stderr, "clf-filter:", res.err)
+			failed = true
+		}
+		if res.name != "-" || res.counts.long != 0 || res.counts.malformed != 0 {
+			fmt.Fprintf(os.Stderr, "clf-filter: %s: %s\n", res.name, res.counts.summary(longAction))
+		}
+		total.add(res.counts)
+	}
+	if len(results) > 1 {
+		fmt.Fprintf(os.Stderr, "clf-filter: total: %s\n", total.summary(longAction))
+	}
 
-		if *botOnly == isBot {
-			fmt.Println(line)
+	if f.metrics != nil {
+		if err := writeMetrics(f.metrics, *metricsFile); err != nil {
+			fmt.Fprintln(os.Stderr, "clf-filter: failed to write metrics:", err)
+			os.Exit(1)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintln(os.Stderr, "clf-filter: read error:", err)
+	if failed {
 		os.Exit(1)
 	}
 }
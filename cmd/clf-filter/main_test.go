@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	agents "github.com/monperrus/crawler-user-agents"
+	"github.com/monperrus/crawler-user-agents/internal/accesslog"
+)
+
+// TestNormalize classifies the mangled User Agents of testdata/mangled.log:
+// the first four lines are crawlers, the others are browsers.
+func TestNormalize(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "mangled.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	matcher, err := agents.NewMatcherWithOptions(agents.Crawlers, agents.MatcherOptions{Normalize: agents.NormalizeUserAgent})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		ua, ok := accesslog.UserAgent(scanner.Text())
+		if !ok {
+			t.Fatalf("line %d: no User Agent", i+1)
+		}
+		wantBot := i < 4
+		if matcher.IsCrawler(ua) != wantBot {
+			t.Errorf("line %d: IsCrawler(%q) = %v with normalization", i+1, ua, !wantBot)
+		}
+		if i == 0 && agents.IsCrawler(ua) {
+			t.Errorf("line %d: URL-encoded %q is detected without normalization", i+1, ua)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	files, err := expandArgs([]string{filepath.Join("testdata", "access.log*"), "missing.log"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		filepath.Join("testdata", "access.log"),
+		filepath.Join("testdata", "access.log.1.gz"),
+		filepath.Join("testdata", "access.log.2.bz2"),
+		"missing.log",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got %v, want %v", files, want)
+	}
+
+	if _, err := expandArgs([]string{filepath.Join("testdata", "*.missing")}); err == nil {
+		t.Errorf("expected an error for a glob without matches")
+	}
+}
+
+func TestFilterInputs(t *testing.T) {
+	names, err := expandArgs([]string{filepath.Join("testdata", "access.log*")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCounts := []counts{
+		{lines: 4, bots: 2, kept: 2},
+		{lines: 3, bots: 2, kept: 2},
+		{lines: 2, bots: 1, kept: 1},
+	}
+
+	var outputs []string
+	for _, parallel := range []int{1, 3} {
+		f := &filter{matcher: agents.DefaultMatcher(), botOnly: true}
+		var b strings.Builder
+		out := newOutput(&b)
+		results := f.filterInputs(names, parallel, out)
+		if err := out.flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, res := range results {
+			if res.err != nil || res.name != names[i] || res.counts != wantCounts[i] {
+				t.Errorf("parallel %d: got result %+v, want counts %+v of %s", parallel, res, wantCounts[i], names[i])
+			}
+		}
+
+		lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+		sort.Strings(lines)
+		outputs = append(outputs, strings.Join(lines, "\n"))
+		if len(lines) != 5 {
+			t.Errorf("parallel %d: got %d lines, want 5:\n%s", parallel, len(lines), b.String())
+		}
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("parallel output differs from sequential one")
+	}
+
+	f := &filter{matcher: agents.DefaultMatcher()}
+	res := f.filterInputs([]string{filepath.Join("testdata", "missing.log")}, 1, newOutput(io.Discard))
+	if res[0].err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+// selection selects bot lines by the crawlers matching their User Agent.
+type selection struct {
+	// include, if not empty, selects lines matching a crawler having one of
+	// the tags.
+	include agents.TagSet
+
+	// exclude rejects lines matching a crawler having one of the tags.
+	exclude agents.TagSet
+
+	// pattern, if not empty, selects lines matching a crawler whose pattern
+	// contains it, e.g. "Googlebot".
+	pattern string
+}
+
+// parseTags parses a comma-separated list of tags.
+func parseTags(s string) (agents.TagSet, error) {
+	var tags agents.TagSet
+	if s == "" {
+		return tags, nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		tag, err := agents.ParseTag(name)
+		if err != nil {
+			return 0, err
+		}
+		tags |= agents.NewTagSet(tag)
+	}
+	return tags, nil
+}
+
+// empty reports if the selection selects all bot lines.
+func (s selection) empty() bool {
+	return s.include == 0 && s.exclude == 0 && s.pattern == ""
+}
+
+// selects reports if a line whose User Agent matches the crawlers with the
+// indices is selected. Tags are those of all matching crawlers, so that
+// e.g. excluding search-engine rejects a Googlebot line also matching a generic
+// pattern.
+func (s selection) selects(m *agents.Matcher, indices []int) bool {
+	if len(indices) == 0 {
+		return false
+	}
+
+	var tags agents.TagSet
+	patternFound := s.pattern == ""
+	for _, index := range indices {
+		tags |= m.CrawlerTags(index)
+		if !patternFound && strings.Contains(m.Crawlers()[index].Pattern, s.pattern) {
+			patternFound = true
+		}
+	}
+
+	switch {
+	case !patternFound:
+		return false
+	case s.include != 0 && !tags.HasAny(s.include):
+		return false
+	case tags.HasAny(s.exclude):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+func TestSelection(t *testing.T) {
+	const (
+		gptbotLine = `1.2.3.4 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; GPTBot/1.0; +https://openai.com/gptbot)"`
+		curlLine   = `{"status":200,"http_user_agent":"curl/8.4.0"}`
+	)
+	lines := []string{googlebotLine, gptbotLine, browserLine, curlLine}
+
+	cases := []struct {
+		name      string
+		botOnly   bool
+		include   []agents.Tag
+		exclude   []agents.Tag
+		pattern   string
+		invert    bool
+		wantLines []string
+	}{
+		{name: "default", wantLines: []string{browserLine}},
+		{name: "bot", botOnly: true, wantLines: []string{googlebotLine, gptbotLine, curlLine}},
+		{name: "invert", invert: true, wantLines: []string{googlebotLine, gptbotLine, curlLine}},
+		{name: "include", include: []agents.Tag{agents.TagAICrawler}, wantLines: []string{googlebotLine, browserLine, curlLine}},
+		{name: "include several", include: []agents.Tag{agents.TagAICrawler, agents.TagHTTPLibrary}, wantLines: []string{googlebotLine, browserLine}},
+		{name: "exclude", exclude: []agents.Tag{agents.TagSearchEngine}, wantLines: []string{googlebotLine, browserLine}},
+		{name: "pattern", pattern: "Googlebot", wantLines: []string{gptbotLine, browserLine, curlLine}},
+		{name: "pattern and include", pattern: "Googlebot", include: []agents.Tag{agents.TagAICrawler}, wantLines: lines},
+		{name: "invert include", include: []agents.Tag{agents.TagAICrawler}, invert: true, wantLines: []string{gptbotLine}},
+		{name: "bot include", botOnly: true, include: []agents.Tag{agents.TagAICrawler}, wantLines: []string{gptbotLine}},
+		{name: "bot include several", botOnly: true, include: []agents.Tag{agents.TagAICrawler, agents.TagHTTPLibrary}, wantLines: []string{gptbotLine, curlLine}},
+		{name: "bot exclude", botOnly: true, exclude: []agents.Tag{agents.TagSearchEngine}, wantLines: []string{gptbotLine, curlLine}},
+		{name: "bot pattern", botOnly: true, pattern: "Googlebot", wantLines: []string{googlebotLine}},
+		{name: "bot pattern and include", botOnly: true, pattern: "Googlebot", include: []agents.Tag{agents.TagAICrawler}},
+		{name: "bot invert include", botOnly: true, include: []agents.Tag{agents.TagAICrawler}, invert: true, wantLines: []string{googlebotLine, browserLine, curlLine}},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			f := &filter{
+				matcher: agents.DefaultMatcher(),
+				botOnly: tc.botOnly,
+				selection: selection{
+					include: agents.NewTagSet(tc.include...),
+					exclude: agents.NewTagSet(tc.exclude...),
+					pattern: tc.pattern,
+				},
+				invert:  tc.invert,
+				uaField: "http_user_agent",
+			}
+			var c counts
+			var got []string
+			for _, line := range lines {
+				if line, ok, _ := f.process(line, false, &c); ok {
+					got = append(got, line)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("got lines %q, want %q", got, tc.wantLines)
+			}
+			if want := (counts{lines: 4, bots: 3, kept: len(tc.wantLines)}); c != want {
+				t.Errorf("got counts %+v, want %+v", c, want)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tags, err := parseTags("ai-crawler,seo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := agents.NewTagSet(agents.TagAICrawler, agents.TagSEO); tags != want {
+		t.Errorf("parseTags = %v, want %v", tags, want)
+	}
+	if _, err := parseTags("ai-crawler,robot"); err == nil {
+		t.Error("unknown tag is accepted")
+	}
+}
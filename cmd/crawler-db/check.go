@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+// unescapedRe finds slashes and dots which are not escaped, as validate.py.
+var unescapedRe = regexp.MustCompile(`(^|[^\\])[/.]`)
+
+// problems is the list of problems found by check.
+type problems []error
+
+func (p problems) Error() string {
+	messages := make([]string, len(p))
+	for i, err := range p {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add adds a problem.
+func (p *problems) add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Errorf(format, args...))
+}
+
+// check validates the crawler at index i against the whole list: the rules of
+// validate.py for the entry, and no overlap with the other crawlers. Its
+// pattern must match its instances and no browser of BrowserUserAgents, its
+// instances must not be matched by other crawlers and it must not match their
+// instances, and patterns must not match each other. As for validate.py, an
+// instance may also be matched by the crawlers listed in depends_on of its
+// entry, which must be in the list. All problems are returned, so that an
+// edit can be checked for new ones; if the patterns do not compile, overlaps
+// are not checked.
+func check(db *database, i int) problems {
+	var p problems
+	crawlers := db.crawlers
+	crawler := crawlers[i]
+	if loc := unescapedRe.FindStringIndex(crawler.Pattern); loc != nil {
+		p.add("pattern %q has an unescaped %q", crawler.Pattern, crawler.Pattern[loc[1]-1])
+	}
+	if len(crawler.Instances) == 0 {
+		p.add("pattern %q has no instances", crawler.Pattern)
+	}
+	if crawler.Operator != "" {
+		if _, ok := agents.LookupOperator(crawler.Operator); !ok {
+			p.add("pattern %q has unknown operator %q, add it to operators.json", crawler.Pattern, crawler.Operator)
+		}
+	}
+	for _, pattern := range db.dependsOn[i] {
+		if db.find(pattern) < 0 {
+			p.add("pattern %q depends on %q, which is not in the list", crawler.Pattern, pattern)
+		}
+	}
+
+	m, err := agents.NewMatcher(crawlers)
+	if err != nil {
+		return append(p, err)
+	}
+	own, err := agents.NewMatcher([]agents.Crawler{crawler})
+	if err != nil {
+		return append(p, err)
+	}
+	var version *regexp.Regexp
+	if crawler.VersionPattern != "" {
+		version = regexp.MustCompile(crawler.VersionPattern) // Compiled by NewMatcher.
+	}
+
+	seen := make(map[string]bool)
+	for _, instance := range crawler.Instances {
+		if seen[instance] {
+			p.add("pattern %q has duplicate instance %q", crawler.Pattern, instance)
+			continue
+		}
+		seen[instance] = true
+		if !own.IsCrawler(instance) {
+			p.add("pattern %q misses instance %q", crawler.Pattern, instance)
+		}
+		for _, j := range m.MatchingCrawlers(instance) {
+			if j != i && !db.dependsOnPattern(i, crawlers[j].Pattern) {
+				p.add("instance %q of pattern %q is also matched by pattern %q", instance, crawler.Pattern, crawlers[j].Pattern)
+			}
+		}
+		if version != nil && version.FindStringSubmatch(instance) == nil {
+			p.add("version pattern %q misses instance %q", crawler.VersionPattern, instance)
+		}
+	}
+	for _, browser := range agents.BrowserUserAgents {
+		if own.IsCrawler(browser) {
+			// One browser is enough to report the problem.
+			p.add("pattern %q matches browser %q", crawler.Pattern, browser)
+			break
+		}
+	}
+
+	re, err := regexp.Compile("(?i)" + crawler.Pattern)
+	if err != nil {
+		return append(p, err)
+	}
+	for j, other := range crawlers {
+		if j == i {
+			continue
+		}
+		if strings.EqualFold(other.Pattern, crawler.Pattern) {
+			p.add("pattern %q is already in the list as %q", crawler.Pattern, other.Pattern)
+			continue
+		}
+		if re.MatchString(other.Pattern) {
+			p.add("pattern %q is a subset of %q", other.Pattern, crawler.Pattern)
+		}
+		if otherRe, err := regexp.Compile("(?i)" + other.Pattern); err == nil && otherRe.MatchString(crawler.Pattern) {
+			p.add("pattern %q is a subset of %q", crawler.Pattern, other.Pattern)
+		}
+		if db.dependsOnPattern(j, crawler.Pattern) {
+			continue
+		}
+		for _, instance := range other.Instances {
+			if own.IsCrawler(instance) {
+				p.add("pattern %q matches instance %q of pattern %q", crawler.Pattern, instance, other.Pattern)
+				break
+			}
+		}
+	}
+	return p
+}
+
+// newProblems returns the problems of after which are not in before.
+func newProblems(before, after problems) problems {
+	known := make(map[string]bool, len(before))
+	for _, err := range before {
+		known[err.Error()] = true
+	}
+	var added problems
+	for _, err := range after {
+		if !known[err.Error()] {
+			added = append(added, err)
+		}
+	}
+	return added
+}
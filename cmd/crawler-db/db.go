@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	agents "github.com/monperrus/crawler-user-agents"
+	"github.com/monperrus/crawler-user-agents/internal/jsonformat"
+)
+
+// crawlerKeys are the keys written by Crawler.MarshalJSON. Other keys of the
+// entries (e.g. depends_on) are kept as they are when an entry is updated.
+var crawlerKeys = func() map[string]bool {
+	keys := make(map[string]bool, len(agents.CrawlerFields))
+	for _, field := range agents.CrawlerFields {
+		keys[field] = true
+	}
+	return keys
+}()
+
+// database is the list of crawlers of crawler-user-agents.json. Entries are
+// kept as they are in the file, so that only added and updated entries are
+// written again.
+type database struct {
+	entries  []json.RawMessage
+	crawlers []agents.Crawler
+
+	// dependsOn are the patterns of depends_on of the entries, whose
+	// crawlers may also match their instances.
+	dependsOn [][]string
+}
+
+func parseDatabase(data []byte) (*database, error) {
+	db := &database{}
+	if err := json.Unmarshal(data, &db.entries); err != nil {
+		return nil, err
+	}
+	db.crawlers = make([]agents.Crawler, len(db.entries))
+	db.dependsOn = make([][]string, len(db.entries))
+	for i, entry := range db.entries {
+		if err := json.Unmarshal(entry, &db.crawlers[i]); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		var deps struct {
+			DependsOn []string `json:"depends_on"`
+		}
+		if err := json.Unmarshal(entry, &deps); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		db.dependsOn[i] = deps.DependsOn
+	}
+	return db, nil
+}
+
+// find returns the index of the crawler with the pattern, or -1.
+func (db *database) find(pattern string) int {
+	for i, crawler := range db.crawlers {
+		if crawler.Pattern == pattern {
+			return i
+		}
+	}
+	return -1
+}
+
+// dependsOnPattern reports if the crawler at index i lists the pattern in
+// depends_on.
+func (db *database) dependsOnPattern(i int, pattern string) bool {
+	for _, p := range db.dependsOn[i] {
+		if p == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// dependents returns the patterns of the crawlers listing the pattern in
+// depends_on.
+func (db *database) dependents(pattern string) []string {
+	var patterns []string
+	for i, crawler := range db.crawlers {
+		if db.dependsOnPattern(i, pattern) {
+			patterns = append(patterns, crawler.Pattern)
+		}
+	}
+	return patterns
+}
+
+// add appends the crawler and returns its index. Empty values which Crawler
+// writes (e.g. a missing url) are left out of the entry.
+func (db *database) add(crawler agents.Crawler) (int, error) {
+	data, err := json.Marshal(crawler)
+	if err != nil {
+		return 0, err
+	}
+	keys, values, err := objectFields(data)
+	if err != nil {
+		return 0, err
+	}
+	kept := keys[:0]
+	for _, key := range keys {
+		if !emptyValue(values[key]) {
+			kept = append(kept, key)
+		}
+	}
+	db.entries = append(db.entries, writeObject(kept, values))
+	db.crawlers = append(db.crawlers, crawler)
+	db.dependsOn = append(db.dependsOn, nil)
+	return len(db.crawlers) - 1, nil
+}
+
+// update replaces the crawler at index i. Keys of the entry keep their order,
+// new keys are appended, and keys which are not written by Crawler any more
+// (e.g. an emptied description) are removed.
+func (db *database) update(i int, crawler agents.Crawler) error {
+	data, err := json.Marshal(crawler)
+	if err != nil {
+		return err
+	}
+	newKeys, values, err := objectFields(data)
+	if err != nil {
+		return err
+	}
+	keys, old, err := objectFields(db.entries[i])
+	if err != nil {
+		return err
+	}
+
+	var written []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		seen[key] = true
+		if _, ok := values[key]; ok {
+			written = append(written, key)
+		} else if !crawlerKeys[key] {
+			written = append(written, key)
+			values[key] = old[key]
+		}
+	}
+	for _, key := range newKeys {
+		if !seen[key] && !emptyValue(values[key]) {
+			written = append(written, key)
+		}
+	}
+
+	db.entries[i] = writeObject(written, values)
+	db.crawlers[i] = crawler
+	return nil
+}
+
+// setDependsOn replaces depends_on of the entry at index i, keeping its place
+// among the keys. An empty list removes the key.
+func (db *database) setDependsOn(i int, patterns []string) error {
+	keys, values, err := objectFields(db.entries[i])
+	if err != nil {
+		return err
+	}
+	if len(patterns) == 0 {
+		kept := keys[:0]
+		for _, key := range keys {
+			if key != "depends_on" {
+				kept = append(kept, key)
+			}
+		}
+		keys = kept
+	} else {
+		if _, ok := values["depends_on"]; !ok {
+			keys = append(keys, "depends_on")
+		}
+		if values["depends_on"], err = json.Marshal(patterns); err != nil {
+			return err
+		}
+	}
+
+	db.entries[i] = writeObject(keys, values)
+	db.dependsOn[i] = patterns
+	return nil
+}
+
+// remove removes the crawler at index i.
+func (db *database) remove(i int) {
+	db.entries = append(db.entries[:i], db.entries[i+1:]...)
+	db.crawlers = append(db.crawlers[:i], db.crawlers[i+1:]...)
+	db.dependsOn = append(db.dependsOn[:i], db.dependsOn[i+1:]...)
+}
+
+// bytes returns the list in the format of format.js.
+func (db *database) bytes() ([]byte, error) {
+	data, err := json.Marshal(db.entries)
+	if err != nil {
+		return nil, err
+	}
+	return jsonformat.Format(data)
+}
+
+// objectFields returns the keys of the JSON object in order and their values.
+func objectFields(data []byte) ([]string, map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	values := make(map[string]json.RawMessage)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := token.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	return keys, values, nil
+}
+
+// writeObject returns the JSON object of the keys, in order, and their values.
+func writeObject(keys []string, values map[string]json.RawMessage) json.RawMessage {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(values[key])
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
+
+// emptyValue reports if the value is null or "", which Crawler writes for
+// fields without omitempty.
+func emptyValue(value json.RawMessage) bool {
+	s := strings.TrimSpace(string(value))
+	return s == "null" || s == `""`
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const exampleUA = "Mozilla/5.0 (compatible; ExampleBot/1.2; +https://example.com/bot)"
+
+var testNow = time.Date(2026, 10, 19, 15, 4, 5, 0, time.Local)
+
+func loadTestDatabase(t *testing.T) (*database, string) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/crawlers.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := parseDatabase(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, string(data)
+}
+
+func output(t *testing.T, db *database) string {
+	t.Helper()
+	data, err := db.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// entry returns the entry of the formatted list starting with the pattern.
+func entry(t *testing.T, list, pattern string) string {
+	t.Helper()
+	start := strings.Index(list, "  {\n    \"pattern\": \""+pattern+"\"")
+	if start < 0 {
+		t.Fatalf("no entry %q in\n%s", pattern, list)
+	}
+	end := strings.Index(list[start:], "\n  }")
+	return list[start : start+end+len("\n  }")]
+}
+
+func TestRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../crawler-user-agents.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := parseDatabase(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := output(t, db); got != string(data) {
+		t.Error("crawler-user-agents.json is not written unchanged")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	db, original := loadTestDatabase(t)
+	args := []string{"-pattern", "ExampleBot", "-url", "https://example.com/bot", "-tags", "search-engine, ai-crawler", "-description", "Example <crawler>", exampleUA}
+	if err := addCommand(db, args, testNow); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.TrimSuffix(original, "\n]\n") + `,
+  {
+    "pattern": "ExampleBot",
+    "addition_date": "2026/10/19",
+    "url": "https://example.com/bot",
+    "instances": [
+      "Mozilla/5.0 (compatible; ExampleBot/1.2; +https://example.com/bot)"
+    ],
+    "description": "Example <crawler>",
+    "tags": [
+      "search-engine",
+      "ai-crawler"
+    ]
+  }
+]
+`
+	if got := output(t, db); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// Without -url, the entry has no url rather than an empty one.
+	if err := addCommand(db, []string{"-pattern", "OtherBot", "OtherBot/1.0"}, testNow); err != nil {
+		t.Fatal(err)
+	}
+	want = `  {
+    "pattern": "OtherBot",
+    "addition_date": "2026/10/19",
+    "instances": [
+      "OtherBot/1.0"
+    ]
+  }`
+	if e := entry(t, output(t, db), "OtherBot"); e != want {
+		t.Errorf("got\n%s\nwant\n%s", e, want)
+	}
+}
+
+func TestAddErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		args  []string
+		want  string
+		usage bool
+	}{
+		{"no instance", []string{"-pattern", "ExampleBot"}, "at least one instance", true},
+		{"unknown tag", []string{"-pattern", "ExampleBot", "-tags", "robot", exampleUA}, "robot", true},
+		{"existing", []string{"-pattern", `Googlebot\/`, exampleUA}, "already in the list", false},
+		{"missed instance", []string{"-pattern", "OtherBot", exampleUA}, "misses instance", false},
+		{"unescaped dot", []string{"-pattern", "ExampleBot.1", "ExampleBot.1"}, "unescaped", false},
+		{"unknown operator", []string{"-pattern", "ExampleBot", "-operator", "example", exampleUA}, "unknown operator", false},
+		{"browser", []string{"-pattern", "Mozilla", exampleUA}, "matches browser", false},
+		{"matched instance", []string{"-pattern", "ExampleBot", "Googlebot/2.1 ExampleBot"}, "also matched by pattern", false},
+		{"other instance", []string{"-pattern", "iPhone OS 3", "ExampleBot (iPhone OS 3)"}, "matches instance", false},
+		{"subset", []string{"-pattern", "Googlebot", "Googlebot ExampleBot"}, "subset", false},
+		{"same pattern", []string{"-pattern", "JYXOBOT", "JYXOBOT/1.0"}, "already in the list", false},
+		{"unknown depends_on", []string{"-pattern", "ExampleBot", "-depends-on", "NoSuchBot", exampleUA}, "not in the list", false},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			db, _ := loadTestDatabase(t)
+			err := addCommand(db, tc.args, testNow)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("got error %v, want %q", err, tc.want)
+			}
+			var usage usageError
+			if errors.As(err, &usage) != tc.usage {
+				t.Errorf("usage error: %v, want %v", !tc.usage, tc.usage)
+			}
+		})
+	}
+}
+
+func TestEdit(t *testing.T) {
+	db, original := loadTestDatabase(t)
+	args := []string{"-pattern", `Googlebot\/`, "-description", "", "-tags", "search-engine,archiver", "Googlebot/2.1 (+http://www.google.com/bot.html)"}
+	if err := editCommand(db, args); err != nil {
+		t.Fatal(err)
+	}
+	if err := editCommand(db, []string{"-pattern", "AdsBot-Google-Mobile", "-url", "https://developers.google.com/search/docs/crawling-indexing/google-special-case-crawlers"}); err != nil {
+		t.Fatal(err)
+	}
+	got := output(t, db)
+
+	want := `  {
+    "pattern": "Googlebot\\/",
+    "url": "http://www.google.com/bot.html",
+    "instances": [
+      "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+      "Googlebot/2.1 (+http://www.google.com/bot.html)"
+    ],
+    "addition_date": "2014/02/28",
+    "tags": [
+      "search-engine",
+      "archiver"
+    ]
+  }`
+	if e := entry(t, got, `Googlebot\\/`); e != want {
+		t.Errorf("got\n%s\nwant\n%s", e, want)
+	}
+	want = `  {
+    "pattern": "AdsBot-Google-Mobile",
+    "addition_date": "2016/01/01",
+    "instances": [
+      "AdsBot-Google-Mobile (+http://www.google.com/mobile/adsbot.html) Mozilla (iPhone; U; CPU iPhone OS 3 0 like Mac OS X) AppleWebKit (KHTML, like Gecko) Mobile Safari"
+    ],
+    "depends_on": [
+      "AdsBot-Google"
+    ],
+    "operator": "google",
+    "url": "https://developers.google.com/search/docs/crawling-indexing/google-special-case-crawlers"
+  }`
+	if e := entry(t, got, "AdsBot-Google-Mobile"); e != want {
+		t.Errorf("got\n%s\nwant\n%s", e, want)
+	}
+	if e, o := entry(t, got, "jyxobot"), entry(t, original, "jyxobot"); e != o {
+		t.Errorf("untouched entry changed:\n%s", e)
+	}
+}
+
+func TestEditErrors(t *testing.T) {
+	db, _ := loadTestDatabase(t)
+	if err := editCommand(db, []string{"-pattern", "ExampleBot"}); err == nil || !strings.Contains(err.Error(), "not in the list") {
+		t.Errorf("got error %v for an unknown pattern", err)
+	}
+	if err := editCommand(db, []string{"-pattern", `Googlebot\/`, "-new-pattern", "Google"}); err == nil || !strings.Contains(err.Error(), "subset") {
+		t.Errorf("got error %v for an overlapping pattern", err)
+	}
+
+	// jyxobot has no instances: editing it is allowed, unless the edit adds
+	// a problem.
+	db, _ = loadTestDatabase(t)
+	if err := editCommand(db, []string{"-pattern", "jyxobot", "-url", "http://jyxo.cz"}); err != nil {
+		t.Errorf("got error %v for an entry which was already invalid", err)
+	}
+	err := editCommand(db, []string{"-pattern", "jyxobot", "-version-pattern", "(", "-operator", "no-such-op"})
+	if err == nil || !strings.Contains(err.Error(), "unknown operator") || !strings.Contains(err.Error(), "missing closing )") {
+		t.Errorf("got error %v for an invalid version pattern and operator", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "no instances") {
+		t.Errorf("error %v reports a problem which was already there", err)
+	}
+
+	// A refused edit is not written.
+	db, _ = loadTestDatabase(t)
+	if err := editCommand(db, []string{"-pattern", "jyxobot", "-url", "http://jyxo.cz", "Googlebot/2.1"}); err == nil || !strings.Contains(err.Error(), "misses instance") {
+		t.Errorf("got error %v for a new problem", err)
+	}
+	if err := editCommand(db, []string{"-pattern", "jyxobot", "jyxobot/1.0"}); err != nil {
+		t.Errorf("got error %v when fixing an entry", err)
+	}
+}
+
+// TestEditEmptyInstances checks that an entry without instances keeps an
+// empty list rather than null.
+func TestEditEmptyInstances(t *testing.T) {
+	db, _ := loadTestDatabase(t)
+	if err := editCommand(db, []string{"-pattern", "jyxobot", "-url", "http://jyxo.cz"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `  {
+    "pattern": "jyxobot",
+    "instances": [],
+    "url": "http://jyxo.cz"
+  }`
+	if e := entry(t, output(t, db), "jyxobot"); e != want {
+		t.Errorf("got\n%s\nwant\n%s", e, want)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	db, original := loadTestDatabase(t)
+	if err := removeCommand(db, []string{"AdsBot-Google-Mobile"}); err != nil {
+		t.Fatal(err)
+	}
+	got := output(t, db)
+	want := strings.Replace(original, entry(t, original, "AdsBot-Google-Mobile")+",\n", "", 1)
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if err := removeCommand(db, []string{"AdsBot-Google-Mobile"}); err == nil {
+		t.Error("no error removing an unknown pattern")
+	}
+}
+
+// TestDependsOn checks that the crawlers of depends_on may match the instances
+// of an entry, and cannot be removed while the entry names them.
+func TestDependsOn(t *testing.T) {
+	db, _ := loadTestDatabase(t)
+	if err := addCommand(db, []string{"-pattern", "ExampleBot", exampleUA}, testNow); err != nil {
+		t.Fatal(err)
+	}
+	fetchArgs := []string{"-pattern", "ExampleFetch", "ExampleFetch/1.0 ExampleBot/1.2"}
+	if err := addCommand(db, fetchArgs, testNow); err == nil || !strings.Contains(err.Error(), "also matched by pattern") {
+		t.Fatalf("got error %v for an instance matched by another crawler", err)
+	}
+
+	db, _ = loadTestDatabase(t)
+	if err := addCommand(db, []string{"-pattern", "ExampleBot", exampleUA}, testNow); err != nil {
+		t.Fatal(err)
+	}
+	if err := addCommand(db, append([]string{"-depends-on", "ExampleBot"}, fetchArgs...), testNow); err != nil {
+		t.Fatalf("got error %v with depends_on", err)
+	}
+	want := `  {
+    "pattern": "ExampleFetch",
+    "addition_date": "2026/10/19",
+    "instances": [
+      "ExampleFetch/1.0 ExampleBot/1.2"
+    ],
+    "depends_on": [
+      "ExampleBot"
+    ]
+  }`
+	if e := entry(t, output(t, db), "ExampleFetch"); e != want {
+		t.Errorf("got\n%s\nwant\n%s", e, want)
+	}
+	// ExampleBot may match the instances of ExampleFetch.
+	if err := editCommand(db, []string{"-pattern", "ExampleBot", "ExampleBot/1.3"}); err != nil {
+		t.Errorf("got error %v editing a crawler named in depends_on", err)
+	}
+
+	err := removeCommand(db, []string{"ExampleBot"})
+	if err == nil || !strings.Contains(err.Error(), "ExampleFetch") {
+		t.Errorf("got error %v removing a crawler named in depends_on", err)
+	}
+	if db.find("ExampleBot") < 0 {
+		t.Error("refused removal removed the crawler")
+	}
+	if err := removeCommand(db, []string{"ExampleBot", "ExampleFetch"}); err != nil {
+		t.Errorf("got error %v removing a crawler with its dependents", err)
+	}
+
+	// An empty -depends-on removes depends_on.
+	if err := editCommand(db, []string{"-pattern", "AdsBot-Google-Mobile", "-depends-on", ""}); err != nil {
+		t.Fatal(err)
+	}
+	if e := entry(t, output(t, db), "AdsBot-Google-Mobile"); strings.Contains(e, "depends_on") {
+		t.Errorf("depends_on is not removed:\n%s", e)
+	}
+}
+
+func TestTag(t *testing.T) {
+	db, _ := loadTestDatabase(t)
+	if err := tagCommand(db, []string{"-pattern", "jyxobot", "search-engine", "archiver"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tagCommand(db, []string{"-pattern", `Googlebot\/`, "-remove", "search-engine"}); err != nil {
+		t.Fatal(err)
+	}
+	got := output(t, db)
+
+	want := `  {
+    "pattern": "jyxobot",
+    "instances": [],
+    "tags": [
+      "search-engine",
+      "archiver"
+    ]
+  }`
+	if e := entry(t, got, "jyxobot"); e != want {
+		t.Errorf("got\n%s\nwant\n%s", e, want)
+	}
+	if e := entry(t, got, `Googlebot\\/`); strings.Contains(e, "tags") {
+		t.Errorf("tags are not removed:\n%s", e)
+	}
+
+	if err := tagCommand(db, []string{"-pattern", "jyxobot", "robot"}); err == nil {
+		t.Error("no error for an unknown tag")
+	}
+}
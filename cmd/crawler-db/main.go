@@ -0,0 +1,390 @@
+// crawler-db adds, edits and removes entries of crawler-user-agents.json and
+// writes it in the format of format.js, so that a contribution is one command
+// rather than an edit of the JSON file by hand:
+//
+//	crawler-db add -pattern ExampleBot -url https://example.com/bot -tags search-engine \
+//		'Mozilla/5.0 (compatible; ExampleBot/1.2; +https://example.com/bot)'
+//	crawler-db edit -pattern ExampleBot -description 'Crawler of Example' 'ExampleBot/2.0'
+//	crawler-db tag -pattern ExampleBot ai-crawler
+//	crawler-db tag -pattern ExampleBot -remove search-engine
+//	crawler-db remove ExampleBot
+//
+// add sets addition_date to the current date and appends the entry. edit
+// replaces the fields given as flags and adds the instances given as
+// arguments; the other fields and the order of the keys of the entry are
+// kept. -depends-on sets depends_on, the crawlers which may also match the
+// instances of the entry. remove refuses to remove a crawler which other
+// entries list in depends_on. Untouched entries are written unchanged.
+//
+// Added and edited entries are validated as by validate.py and must not
+// overlap with other crawlers: their pattern must match their instances and
+// no browser of browser-user-agents.json, their instances must not be matched
+// by other crawlers, and their pattern must not match instances or patterns of
+// other crawlers. An edit of an entry which was already invalid is refused if
+// it adds a problem, e.g. an invalid version pattern to an entry without
+// instances.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+// usageError is an error in the arguments of a command. Errors of flags are
+// already reported by the flag package.
+type usageError struct {
+	err      error
+	reported bool
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Unwrap() error {
+	return e.err
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("crawler-db "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return usageError{err: err, reported: true}
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, ignoring empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseTags(s string) ([]agents.Tag, error) {
+	var tags []agents.Tag
+	for _, name := range splitList(s) {
+		tag, err := agents.ParseTag(name)
+		if err != nil {
+			return nil, usageError{err: err}
+		}
+		tags = appendTag(tags, tag)
+	}
+	return tags, nil
+}
+
+func appendTag(tags []agents.Tag, tag agents.Tag) []agents.Tag {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+// fieldFlags are the flags of add and edit setting fields of a crawler.
+type fieldFlags struct {
+	url, description, operator, versionPattern, tags, robotsTokens, dependsOn *string
+}
+
+func newFieldFlags(fs *flag.FlagSet) fieldFlags {
+	return fieldFlags{
+		url:            fs.String("url", "", "official url of the crawler"),
+		description:    fs.String("description", "", "short description of the crawler"),
+		operator:       fs.String("operator", "", "id of the operator of the crawler in operators.json"),
+		versionPattern: fs.String("version-pattern", "", "regexp with one capture group extracting the version"),
+		tags:           fs.String("tags", "", "comma separated list of tags"),
+		robotsTokens:   fs.String("robots-tokens", "", "comma separated list of robots.txt product tokens"),
+		dependsOn:      fs.String("depends-on", "", "comma separated list of patterns of crawlers which may also match the instances"),
+	}
+}
+
+// apply sets the fields of the crawler whose flag is set.
+func (f fieldFlags) apply(fs *flag.FlagSet, crawler *agents.Crawler) error {
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "url":
+			crawler.URL = *f.url
+		case "description":
+			crawler.Description = *f.description
+		case "operator":
+			crawler.Operator = *f.operator
+		case "version-pattern":
+			crawler.VersionPattern = *f.versionPattern
+		case "tags":
+			crawler.Tags, err = parseTags(*f.tags)
+		case "robots-tokens":
+			crawler.RobotsTokens = splitList(*f.robotsTokens)
+		}
+	})
+	return err
+}
+
+// setDependsOn replaces depends_on of the entry at index i if the flag is set.
+func (f fieldFlags) setDependsOn(fs *flag.FlagSet, db *database, i int) error {
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == "depends-on" {
+			err = db.setDependsOn(i, splitList(*f.dependsOn))
+		}
+	})
+	return err
+}
+
+// addInstances appends the instances which the crawler does not have yet.
+func addInstances(crawler *agents.Crawler, instances []string) {
+	for _, instance := range instances {
+		found := false
+		for _, existing := range crawler.Instances {
+			found = found || existing == instance
+		}
+		if !found {
+			crawler.Instances = append(crawler.Instances, instance)
+		}
+	}
+}
+
+func addCommand(db *database, args []string, now time.Time) error {
+	fs := newFlagSet("add")
+	pattern := fs.String("pattern", "", "pattern of the crawler (required)")
+	fields := newFieldFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: crawler-db add -pattern pattern [flags] instance ...\n")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *pattern == "" || fs.NArg() == 0 {
+		return usageError{err: errors.New("add needs -pattern and at least one instance")}
+	}
+	if i := db.find(*pattern); i >= 0 {
+		return fmt.Errorf("pattern %q is already in the list, use edit", *pattern)
+	}
+
+	crawler := agents.Crawler{
+		Pattern:      *pattern,
+		AdditionDate: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+	}
+	if err := fields.apply(fs, &crawler); err != nil {
+		return err
+	}
+	addInstances(&crawler, fs.Args())
+
+	i, err := db.add(crawler)
+	if err != nil {
+		return err
+	}
+	if err := fields.setDependsOn(fs, db, i); err != nil {
+		return err
+	}
+	if p := check(db, i); len(p) != 0 {
+		return p
+	}
+	return nil
+}
+
+func editCommand(db *database, args []string) error {
+	fs := newFlagSet("edit")
+	pattern := fs.String("pattern", "", "pattern of the crawler to edit (required)")
+	newPattern := fs.String("new-pattern", "", "new pattern of the crawler")
+	fields := newFieldFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: crawler-db edit -pattern pattern [flags] [instance ...]\n")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *pattern == "" {
+		return usageError{err: errors.New("edit needs -pattern")}
+	}
+	i := db.find(*pattern)
+	if i < 0 {
+		return fmt.Errorf("pattern %q is not in the list", *pattern)
+	}
+	before := check(db, i)
+
+	crawler := db.crawlers[i]
+	// Copy the instances, keeping an empty list empty rather than null.
+	instances := make([]string, len(crawler.Instances))
+	copy(instances, crawler.Instances)
+	crawler.Instances = instances
+	if *newPattern != "" {
+		crawler.Pattern = *newPattern
+	}
+	if err := fields.apply(fs, &crawler); err != nil {
+		return err
+	}
+	addInstances(&crawler, fs.Args())
+
+	if err := db.update(i, crawler); err != nil {
+		return err
+	}
+	if err := fields.setDependsOn(fs, db, i); err != nil {
+		return err
+	}
+	if p := newProblems(before, check(db, i)); len(p) != 0 {
+		return p
+	}
+	return nil
+}
+
+func removeCommand(db *database, args []string) error {
+	fs := newFlagSet("remove")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: crawler-db remove pattern ...\n")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError{err: errors.New("remove needs at least one pattern")}
+	}
+	removed := make(map[string]bool)
+	for _, pattern := range fs.Args() {
+		if db.find(pattern) < 0 {
+			return fmt.Errorf("pattern %q is not in the list", pattern)
+		}
+		removed[pattern] = true
+	}
+	for _, pattern := range fs.Args() {
+		var kept []string
+		for _, dependent := range db.dependents(pattern) {
+			if !removed[dependent] {
+				kept = append(kept, dependent)
+			}
+		}
+		if len(kept) != 0 {
+			return fmt.Errorf("pattern %q is in depends_on of %q, edit them first", pattern, kept)
+		}
+	}
+	for _, pattern := range fs.Args() {
+		if i := db.find(pattern); i >= 0 {
+			db.remove(i)
+		}
+	}
+	return nil
+}
+
+// tagCommand adds or removes tags. Tags do not change matching, so the entry
+// is not checked for overlaps.
+func tagCommand(db *database, args []string) error {
+	fs := newFlagSet("tag")
+	pattern := fs.String("pattern", "", "pattern of the crawler to tag (required)")
+	remove := fs.Bool("remove", false, "remove the tags instead of adding them")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: crawler-db tag -pattern pattern [-remove] tag ...\n")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *pattern == "" || fs.NArg() == 0 {
+		return usageError{err: errors.New("tag needs -pattern and at least one tag")}
+	}
+	i := db.find(*pattern)
+	if i < 0 {
+		return fmt.Errorf("pattern %q is not in the list", *pattern)
+	}
+	tags, err := parseTags(strings.Join(fs.Args(), ","))
+	if err != nil {
+		return err
+	}
+
+	crawler := db.crawlers[i]
+	crawler.Tags = append([]agents.Tag(nil), crawler.Tags...)
+	for _, tag := range tags {
+		if !*remove {
+			crawler.Tags = appendTag(crawler.Tags, tag)
+			continue
+		}
+		kept := crawler.Tags[:0]
+		for _, t := range crawler.Tags {
+			if t != tag {
+				kept = append(kept, t)
+			}
+		}
+		crawler.Tags = kept
+	}
+	return db.update(i, crawler)
+}
+
+func run(db *database, command string, args []string) error {
+	switch command {
+	case "add":
+		return addCommand(db, args, time.Now())
+	case "edit":
+		return editCommand(db, args)
+	case "remove":
+		return removeCommand(db, args)
+	case "tag":
+		return tagCommand(db, args)
+	}
+	return usageError{err: fmt.Errorf("unknown command %q", command)}
+}
+
+func main() {
+	file := flag.String("file", "crawler-user-agents.json", "list of crawlers to update")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: crawler-db [-file file] add|edit|remove|tag [flags] [args ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "crawler-db:", err)
+		os.Exit(1)
+	}
+	db, err := parseDatabase(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "crawler-db: %s: %v\n", *file, err)
+		os.Exit(1)
+	}
+
+	if err := run(db, flag.Arg(0), flag.Args()[1:]); err != nil {
+		var usage usageError
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			os.Exit(0)
+		case errors.As(err, &usage):
+			if !usage.reported {
+				fmt.Fprintln(os.Stderr, "crawler-db:", err)
+			}
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "crawler-db:", err)
+		os.Exit(1)
+	}
+
+	data, err = db.bytes()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "crawler-db:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*file, data, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "crawler-db:", err)
+		os.Exit(1)
+	}
+}
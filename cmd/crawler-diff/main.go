@@ -0,0 +1,283 @@
+// crawler-diff compares two versions of crawler-user-agents.json, e.g. before
+// bumping the module, and writes a changelog:
+//
+//	git show v1.0.0:crawler-user-agents.json > old.json
+//	crawler-diff old.json crawler-user-agents.json
+//
+// The changelog lists the crawlers added, removed and changed, keyed by
+// pattern, with the changed fields and tags. It also lists the User Agents of
+// a corpus which the two lists classify differently, e.g. which are crawlers
+// with one list only. The corpus is read from -corpus, one User Agent per
+// line, and defaults to the instances of both lists and the browsers of
+// browser-user-agents.json.
+//
+// Exit status is 0 if the lists are the same, 1 if they differ and 2 on
+// errors, as for diff.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+const (
+	exitSame   = 0
+	exitDiffer = 1
+	exitError  = 2
+)
+
+// report is the structural and behavioural difference of two lists.
+type report struct {
+	agents.DatasetDiff
+	CorpusSize             int                           `json:"corpus_size"`
+	ClassificationChanges  []agents.ClassificationChange `json:"classification_changes"`
+	FlippedClassifications int                           `json:"flipped_classifications"`
+}
+
+func (r report) empty() bool {
+	return r.DatasetDiff.Empty() && len(r.ClassificationChanges) == 0
+}
+
+func loadCrawlers(path string) ([]agents.Crawler, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	crawlers, err := agents.LoadCrawlers(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return crawlers, nil
+}
+
+func readCorpus(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var userAgents []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			userAgents = append(userAgents, line)
+		}
+	}
+	return userAgents, scanner.Err()
+}
+
+// defaultCorpus returns the instances of both lists and BrowserUserAgents.
+func defaultCorpus(old, new []agents.Crawler) []string {
+	var userAgents []string
+	for _, crawlers := range [][]agents.Crawler{old, new} {
+		for _, crawler := range crawlers {
+			userAgents = append(userAgents, crawler.Instances...)
+		}
+	}
+	return append(userAgents, agents.BrowserUserAgents...)
+}
+
+func compare(old, new []agents.Crawler, corpus []string) (report, error) {
+	oldMatcher, err := agents.NewMatcher(old)
+	if err != nil {
+		return report{}, fmt.Errorf("old list: %w", err)
+	}
+	newMatcher, err := agents.NewMatcher(new)
+	if err != nil {
+		return report{}, fmt.Errorf("new list: %w", err)
+	}
+
+	diff, err := agents.DiffCrawlers(old, new)
+	if err != nil {
+		return report{}, err
+	}
+	r := report{
+		DatasetDiff:           diff,
+		ClassificationChanges: agents.DiffClassifications(oldMatcher, newMatcher, corpus),
+	}
+	seen := make(map[string]bool, len(corpus))
+	for _, userAgent := range corpus {
+		seen[userAgent] = true
+	}
+	r.CorpusSize = len(seen)
+	for _, change := range r.ClassificationChanges {
+		if change.Flipped() {
+			r.FlippedClassifications++
+		}
+	}
+	return r, nil
+}
+
+func tagList(tags []agents.Tag) string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = string(tag)
+	}
+	return strings.Join(names, ", ")
+}
+
+func describeCrawler(crawler agents.Crawler) string {
+	s := "`" + crawler.Pattern + "`"
+	if len(crawler.Tags) != 0 {
+		s += " (" + tagList(crawler.Tags) + ")"
+	}
+	if crawler.URL != "" {
+		s += ": " + crawler.URL
+	}
+	return s
+}
+
+func describeChange(change agents.CrawlerChange) string {
+	var parts []string
+	for _, field := range change.Fields {
+		if field != "tags" {
+			parts = append(parts, field)
+			continue
+		}
+		var tags []string
+		for _, tag := range change.AddedTags {
+			tags = append(tags, "+"+string(tag))
+		}
+		for _, tag := range change.RemovedTags {
+			tags = append(tags, "-"+string(tag))
+		}
+		if len(tags) == 0 {
+			parts = append(parts, "tags reordered")
+		} else {
+			parts = append(parts, "tags "+strings.Join(tags, " "))
+		}
+	}
+	return "`" + change.Pattern + "`: " + strings.Join(parts, ", ")
+}
+
+func patternList(patterns []string) string {
+	quoted := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		quoted[i] = "`" + pattern + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// writeMarkdown writes the report as a changelog in Markdown.
+func writeMarkdown(w io.Writer, r report) {
+	fmt.Fprintf(w, "## Crawlers\n\n")
+	if r.DatasetDiff.Empty() {
+		fmt.Fprintf(w, "No changes.\n\n")
+	}
+	if len(r.Added) != 0 {
+		fmt.Fprintf(w, "### Added (%d)\n\n", len(r.Added))
+		for _, crawler := range r.Added {
+			fmt.Fprintf(w, "- %s\n", describeCrawler(crawler))
+		}
+		fmt.Fprintln(w)
+	}
+	if len(r.Removed) != 0 {
+		fmt.Fprintf(w, "### Removed (%d)\n\n", len(r.Removed))
+		for _, crawler := range r.Removed {
+			fmt.Fprintf(w, "- %s\n", describeCrawler(crawler))
+		}
+		fmt.Fprintln(w)
+	}
+	if len(r.Changed) != 0 {
+		fmt.Fprintf(w, "### Changed (%d)\n\n", len(r.Changed))
+		for _, change := range r.Changed {
+			fmt.Fprintf(w, "- %s\n", describeChange(change))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "## Classification\n\n")
+	fmt.Fprintf(w, "%d of %d User-Agents are classified differently, %d of them flip between crawler and not crawler.\n",
+		len(r.ClassificationChanges), r.CorpusSize, r.FlippedClassifications)
+	if len(r.ClassificationChanges) != 0 {
+		fmt.Fprintln(w)
+	}
+	for _, change := range r.ClassificationChanges {
+		switch {
+		case len(change.Old) == 0:
+			fmt.Fprintf(w, "- now a crawler: `%s` (%s)\n", change.UserAgent, patternList(change.New))
+		case len(change.New) == 0:
+			fmt.Fprintf(w, "- no longer a crawler: `%s` (was %s)\n", change.UserAgent, patternList(change.Old))
+		default:
+			fmt.Fprintf(w, "- other crawlers: `%s` (%s, was %s)\n", change.UserAgent, patternList(change.New), patternList(change.Old))
+		}
+	}
+}
+
+// run runs the command and returns its exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("crawler-diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "markdown", "output format: markdown or json")
+	corpusFile := flags.String("corpus", "", "file of User Agents to classify, one per line (default: instances of both lists and browsers)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: crawler-diff [flags] old.json new.json\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 2 || *format != "markdown" && *format != "json" {
+		flags.Usage()
+		return exitError
+	}
+
+	old, err := loadCrawlers(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, "crawler-diff:", err)
+		return exitError
+	}
+	new, err := loadCrawlers(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(stderr, "crawler-diff:", err)
+		return exitError
+	}
+	corpus := defaultCorpus(old, new)
+	if *corpusFile != "" {
+		if corpus, err = readCorpus(*corpusFile); err != nil {
+			fmt.Fprintln(stderr, "crawler-diff:", err)
+			return exitError
+		}
+	}
+
+	r, err := compare(old, new, corpus)
+	if err != nil {
+		fmt.Fprintln(stderr, "crawler-diff:", err)
+		return exitError
+	}
+
+	out := bufio.NewWriter(stdout)
+	if *format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			fmt.Fprintln(stderr, "crawler-diff:", err)
+			return exitError
+		}
+	} else {
+		writeMarkdown(out, r)
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintln(stderr, "crawler-diff:", err)
+		return exitError
+	}
+
+	if !r.empty() {
+		return exitDiffer
+	}
+	return exitSame
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
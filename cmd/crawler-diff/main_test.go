@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+func loadTestCrawlers(t *testing.T, data string) []agents.Crawler {
+	t.Helper()
+	crawlers, err := agents.LoadCrawlers(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crawlers
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	old := loadTestCrawlers(t, `[
+		{"pattern": "examplebot", "instances": ["examplebot/1.0"], "tags": ["search-engine"]},
+		{"pattern": "oldbot", "instances": ["oldbot/1.0"]},
+		{"pattern": "samebot", "instances": ["samebot/1.0"]}
+	]`)
+	new := loadTestCrawlers(t, `[
+		{"pattern": "examplebot", "instances": ["examplebot/1.0"], "tags": ["ai-crawler"], "url": "https://example.com"},
+		{"pattern": "samebot", "instances": ["samebot/1.0"]},
+		{"pattern": "newbot", "instances": ["newbot/1.0"], "tags": ["seo"], "url": "https://example.com/newbot"}
+	]`)
+	r, err := compare(old, new, defaultCorpus(old, new))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	writeMarkdown(&b, r)
+	want := "## Crawlers\n\n" +
+		"### Added (1)\n\n" +
+		"- `newbot` (seo): https://example.com/newbot\n\n" +
+		"### Removed (1)\n\n" +
+		"- `oldbot`\n\n" +
+		"### Changed (1)\n\n" +
+		"- `examplebot`: url, tags +ai-crawler -search-engine\n\n" +
+		"## Classification\n\n" +
+		"2 of " + strconv.Itoa(r.CorpusSize) + " User-Agents are classified differently, 2 of them flip between crawler and not crawler.\n\n" +
+		"- no longer a crawler: `oldbot/1.0` (was `oldbot`)\n" +
+		"- now a crawler: `newbot/1.0` (`newbot`)\n"
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if r.empty() {
+		t.Error("report is empty")
+	}
+
+	if r, err := compare(old, old, defaultCorpus(old, old)); err != nil || !r.empty() {
+		t.Errorf("compare of a list with itself = %+v, %v", r, err)
+	}
+}
+
+func TestRunExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	old := write("old.json", `[{"pattern": "examplebot", "instances": ["examplebot/1.0"]}]`)
+	same := write("same.json", `[
+		{"pattern": "examplebot", "url": "", "instances": ["examplebot/1.0"], "tags": []}
+	]`)
+	new := write("new.json", `[{"pattern": "examplebot", "instances": ["examplebot/1.0"], "tags": ["seo"]}]`)
+	invalid := write("invalid.json", `[{"instances": []}]`)
+	corpus := write("corpus.txt", "examplebot/1.0\n\ncurl/8.4.0\n")
+
+	cases := []struct {
+		name       string
+		args       []string
+		want       int
+		wantOutput string
+	}{
+		{"same", []string{old, same}, exitSame, "No changes."},
+		{"differ", []string{old, new}, exitDiffer, "tags +seo"},
+		{"json", []string{"-format", "json", "-corpus", corpus, old, new}, exitDiffer, `"corpus_size": 2`},
+		{"one file", []string{old}, exitError, ""},
+		{"unknown format", []string{"-format", "text", old, new}, exitError, ""},
+		{"unknown flag", []string{"-x", old, new}, exitError, ""},
+		{"missing file", []string{old, filepath.Join(dir, "missing.json")}, exitError, ""},
+		{"invalid list", []string{old, invalid}, exitError, ""},
+		{"missing corpus", []string{"-corpus", filepath.Join(dir, "missing.txt"), old, new}, exitError, ""},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := run(tc.args, &stdout, &stderr); got != tc.want {
+				t.Errorf("exit status = %d, want %d (stderr %q)", got, tc.want, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tc.wantOutput) {
+				t.Errorf("output %q does not contain %q", stdout.String(), tc.wantOutput)
+			}
+			if tc.want == exitError && (stdout.Len() != 0 || stderr.Len() == 0) {
+				t.Errorf("got output %q and error %q, want an error only", stdout.String(), stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	agents "github.com/monperrus/crawler-user-agents"
+	"github.com/monperrus/crawler-user-agents/internal/accesslog"
+)
+
+// userAgentStats are the requests of one User Agent.
+type userAgentStats struct {
+	userAgent   string
+	requests    int
+	ips         map[string]bool
+	first, last time.Time
+}
+
+// rate returns the number of requests per minute, over at least one minute.
+func (s *userAgentStats) rate() float64 {
+	minutes := s.last.Sub(s.first).Minutes()
+	if minutes < 1 {
+		minutes = 1
+	}
+	return float64(s.requests) / minutes
+}
+
+// collector gathers the User Agents of logs which are not matched by the
+// crawler list.
+type collector struct {
+	matcher *agents.Matcher
+	stats   map[string]*userAgentStats
+	known   map[string]bool
+
+	// Numbers of requests, and of requests of known crawlers.
+	requests int
+	crawlers int
+}
+
+func newCollector(matcher *agents.Matcher) *collector {
+	return &collector{
+		matcher: matcher,
+		stats:   make(map[string]*userAgentStats),
+		known:   make(map[string]bool),
+	}
+}
+
+func (c *collector) add(rec accesslog.Record) {
+	c.requests++
+	if rec.UserAgent == "" || rec.UserAgent == "-" {
+		return
+	}
+	if c.known[rec.UserAgent] {
+		c.crawlers++
+		return
+	}
+	s, ok := c.stats[rec.UserAgent]
+	if !ok {
+		if c.matcher.IsCrawler(rec.UserAgent) {
+			c.known[rec.UserAgent] = true
+			c.crawlers++
+			return
+		}
+		s = &userAgentStats{userAgent: rec.UserAgent, ips: make(map[string]bool)}
+		c.stats[rec.UserAgent] = s
+	}
+	s.requests++
+	s.ips[rec.IP] = true
+	if !rec.Time.IsZero() {
+		if s.first.IsZero() || rec.Time.Before(s.first) {
+			s.first = rec.Time
+		}
+		if rec.Time.After(s.last) {
+			s.last = rec.Time
+		}
+	}
+}
+
+// Signals of bot-like User Agents.
+const (
+	// A token such as "bot", "crawler" or "spider".
+	signalBotToken = "bot-token"
+
+	// A URL, usually documenting the bot.
+	signalURL = "url"
+
+	// Not the structure of a browser User Agent, e.g. no rendering engine.
+	signalNonBrowser = "non-browser"
+
+	// More requests per minute than a person browsing.
+	signalRequestRate = "request-rate"
+)
+
+// signalWeights are the contributions of signals to the score of a User Agent.
+var signalWeights = map[string]int{
+	signalBotToken:    2,
+	signalURL:         2,
+	signalRequestRate: 2,
+	signalNonBrowser:  1,
+}
+
+var (
+	urlRe         = regexp.MustCompile(`\+?(?:https?://|www\.)[^\s;)]+`)
+	botWordRe     = regexp.MustCompile(`(?i)bot|crawl|spider|scrap|fetcher|slurp|archiver`)
+	engineRe      = regexp.MustCompile(`AppleWebKit/|Gecko/|Trident/|Presto/`)
+	wordRe        = regexp.MustCompile(`[A-Za-z][A-Za-z0-9._-]*[A-Za-z0-9]`)
+	productRe     = regexp.MustCompile(`([A-Za-z][A-Za-z0-9._-]*[A-Za-z0-9])/v?[0-9]`)
+	genericTokens = map[string]bool{
+		"Mozilla": true, "AppleWebKit": true, "KHTML": true, "Gecko": true,
+		"Chrome": true, "Safari": true, "Firefox": true, "Version": true,
+		"Mobile": true, "Edg": true, "OPR": true, "Trident": true,
+		"Presto": true, "Opera": true, "compatible": true, "like": true,
+	}
+)
+
+// options configure the detection of candidates.
+type options struct {
+	// Minimum sum of signal weights of a candidate User Agent.
+	minScore int
+
+	// Requests per minute from which a User Agent has signalRequestRate, if
+	// it made at least minRequests requests.
+	minRate     float64
+	minRequests int
+
+	// Maximum number of instances of an entry.
+	maxInstances int
+
+	// Addition date of entries.
+	date time.Time
+}
+
+// signals returns the signals of the User Agent, in the order of their
+// constants.
+func signals(s *userAgentStats, opts options) []string {
+	var found []string
+	withoutURLs := urlRe.ReplaceAllString(s.userAgent, " ")
+	if botWordRe.MatchString(withoutURLs) {
+		found = append(found, signalBotToken)
+	}
+	if withoutURLs != s.userAgent {
+		found = append(found, signalURL)
+	}
+	if !strings.HasPrefix(s.userAgent, "Mozilla/") || !engineRe.MatchString(s.userAgent) {
+		found = append(found, signalNonBrowser)
+	}
+	if s.requests >= opts.minRequests && s.rate() >= opts.minRate {
+		found = append(found, signalRequestRate)
+	}
+	return found
+}
+
+func score(signals []string) int {
+	total := 0
+	for _, signal := range signals {
+		total += signalWeights[signal]
+	}
+	return total
+}
+
+// productToken returns the token naming the product of the User Agent: the
+// first word with a bot-like token, else the first product/version token
+// which is not a browser one, else the first word.
+func productToken(userAgent string) string {
+	withoutURLs := urlRe.ReplaceAllString(userAgent, " ")
+	words := wordRe.FindAllString(withoutURLs, -1)
+	for _, word := range words {
+		if botWordRe.MatchString(word) {
+			return word
+		}
+	}
+	for _, m := range productRe.FindAllStringSubmatch(withoutURLs, -1) {
+		if !genericTokens[m[1]] {
+			return m[1]
+		}
+	}
+	for _, word := range words {
+		if !genericTokens[word] {
+			return word
+		}
+	}
+	return ""
+}
+
+// cluster is a group of candidate User Agents with the same product token.
+type cluster struct {
+	product    string
+	userAgents []*userAgentStats
+	signals    []string
+	requests   int
+	ips        int
+}
+
+// clusters returns the candidates grouped by product token, the clusters with
+// the most requests first.
+func (c *collector) clusters(opts options) []*cluster {
+	byProduct := make(map[string]*cluster)
+	ips := make(map[string]map[string]bool)
+	for _, s := range c.stats {
+		found := signals(s, opts)
+		if score(found) < opts.minScore {
+			continue
+		}
+		product := productToken(s.userAgent)
+		if product == "" {
+			continue
+		}
+		cl, ok := byProduct[product]
+		if !ok {
+			cl = &cluster{product: product}
+			byProduct[product] = cl
+			ips[product] = make(map[string]bool)
+		}
+		cl.userAgents = append(cl.userAgents, s)
+		cl.requests += s.requests
+		cl.signals = mergeSignals(cl.signals, found)
+		for ip := range s.ips {
+			ips[product][ip] = true
+		}
+	}
+
+	clusters := make([]*cluster, 0, len(byProduct))
+	for product, cl := range byProduct {
+		cl.ips = len(ips[product])
+		sort.Slice(cl.userAgents, func(i, j int) bool {
+			a, b := cl.userAgents[i], cl.userAgents[j]
+			if a.requests != b.requests {
+				return a.requests > b.requests
+			}
+			return a.userAgent < b.userAgent
+		})
+		clusters = append(clusters, cl)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].requests != clusters[j].requests {
+			return clusters[i].requests > clusters[j].requests
+		}
+		return clusters[i].product < clusters[j].product
+	})
+	return clusters
+}
+
+// mergeSignals returns the union of the signals, in the order of their
+// constants.
+func mergeSignals(signals, other []string) []string {
+	set := make(map[string]bool)
+	for _, s := range append(signals, other...) {
+		set[s] = true
+	}
+	var merged []string
+	for _, s := range []string{signalBotToken, signalURL, signalNonBrowser, signalRequestRate} {
+		if set[s] {
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
+
+// crawler returns a crawler matching the User Agents of the cluster, to be
+// reviewed before being added to the list.
+func (cl *cluster) crawler(opts options) agents.Crawler {
+	crawler := agents.Crawler{
+		Pattern:      regexp.QuoteMeta(cl.product),
+		AdditionDate: opts.date,
+	}
+	for _, s := range cl.userAgents {
+		if crawler.URL == "" {
+			if url := urlRe.FindString(s.userAgent); url != "" {
+				crawler.URL = strings.TrimPrefix(url, "+")
+				if strings.HasPrefix(crawler.URL, "www.") {
+					crawler.URL = "https://" + crawler.URL
+				}
+			}
+		}
+		if len(crawler.Instances) < opts.maxInstances {
+			crawler.Instances = append(crawler.Instances, s.userAgent)
+		}
+	}
+	return crawler
+}
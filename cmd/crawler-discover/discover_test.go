@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	agents "github.com/monperrus/crawler-user-agents"
+	"github.com/monperrus/crawler-user-agents/internal/accesslog"
+	"github.com/monperrus/crawler-user-agents/internal/jsonformat"
+)
+
+func TestProductToken(t *testing.T) {
+	cases := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Mozilla/5.0 (compatible; ExampleBot/1.2; +https://example.com/bot)", "ExampleBot"},
+		{"Mozilla/5.0 (compatible; Zeta/1.0; +https://zeta.example/bot.html)", "Zeta"},
+		{"SyncTool/3.1 (Windows NT 10.0)", "SyncTool"},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko; acme-crawler) Chrome/120.0 Safari/537.36", "acme-crawler"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36 Acme/2", "Acme"},
+		{"Mozilla/5.0 AppleWebKit/537.36 Chrome/120.0 Safari/537.36 Acme", "Acme"},
+		{"+https://example.com/", ""},
+	}
+	for _, tc := range cases {
+		if got := productToken(tc.userAgent); got != tc.want {
+			t.Errorf("productToken(%q) = %q, want %q", tc.userAgent, got, tc.want)
+		}
+	}
+}
+
+func TestSignals(t *testing.T) {
+	opts := options{minRate: 10, minRequests: 20}
+	cases := []struct {
+		stats userAgentStats
+		want  []string
+	}{
+		{
+			stats: userAgentStats{userAgent: "Mozilla/5.0 (compatible; ExampleBot/1.2; +https://example.com/bot)", requests: 1},
+			want:  []string{signalBotToken, signalURL, signalNonBrowser},
+		},
+		{
+			stats: userAgentStats{userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0", requests: 30},
+			want:  []string{signalRequestRate},
+		},
+		{
+			stats: userAgentStats{userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0", requests: 15},
+		},
+	}
+	for _, tc := range cases {
+		if got := signals(&tc.stats, opts); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("signals(%q) = %v, want %v", tc.stats.userAgent, got, tc.want)
+		}
+	}
+}
+
+// TestDiscover checks the candidates of testdata/access.log: the Googlebot
+// and Firefox requests are skipped, as is a browser User Agent whose only
+// signal is a "Spider" token.
+func TestDiscover(t *testing.T) {
+	c := newCollector(agents.DefaultMatcher())
+	if err := c.readFile(filepath.Join("testdata", "access.log")); err != nil {
+		t.Fatal(err)
+	}
+	if c.requests != 43 || c.crawlers != 3 {
+		t.Errorf("got %d requests, %d of crawlers, want 43, 3", c.requests, c.crawlers)
+	}
+
+	opts := options{
+		minScore:     3,
+		minRate:      10,
+		minRequests:  20,
+		maxInstances: 5,
+		date:         time.Date(2023, 10, 11, 0, 0, 0, 0, time.UTC),
+	}
+	clusters := c.clusters(opts)
+
+	var products []string
+	for _, cl := range clusters {
+		products = append(products, cl.product)
+	}
+	if want := []string{"SyncTool", "ExampleBot", "Zeta"}; !reflect.DeepEqual(products, want) {
+		t.Fatalf("got clusters %q, want %q", products, want)
+	}
+	if cl := clusters[1]; cl.requests != 6 || cl.ips != 2 {
+		t.Errorf("ExampleBot: got %d requests from %d IPs, want 6 from 2", cl.requests, cl.ips)
+	}
+
+	var buf bytes.Buffer
+	if err := writeEntries(&buf, clusters, opts); err != nil {
+		t.Fatal(err)
+	}
+	if formatted, err := jsonformat.Format(buf.Bytes()); err != nil || !bytes.Equal(formatted, buf.Bytes()) {
+		t.Errorf("entries are not formatted as crawler-user-agents.json (%v):\n%s", err, buf.Bytes())
+	}
+	var got, want []agents.Crawler
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want = []agents.Crawler{
+		{
+			Pattern:      "SyncTool",
+			AdditionDate: opts.date,
+			Instances:    []string{"SyncTool/3.1 (Windows NT 10.0)"},
+		},
+		{
+			Pattern:      "ExampleBot",
+			AdditionDate: opts.date,
+			URL:          "https://example.com/bot",
+			Instances: []string{
+				"Mozilla/5.0 (compatible; ExampleBot/1.2; +https://example.com/bot)",
+				"ExampleBot/1.3 (+https://example.com/bot)",
+			},
+		},
+		{
+			Pattern:      "Zeta",
+			AdditionDate: opts.date,
+			URL:          "https://zeta.example/",
+			Instances:    []string{"Mozilla/5.0 (compatible; Zeta/1.0; +https://zeta.example/)"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got entries %+v, want %+v", got, want)
+	}
+
+	// The entries are valid crawlers matching their instances.
+	m, err := agents.NewMatcher(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, crawler := range got {
+		for _, instance := range crawler.Instances {
+			if !contains(m.MatchingCrawlers(instance), i) {
+				t.Errorf("pattern %q does not match %q", crawler.Pattern, instance)
+			}
+		}
+	}
+}
+
+func TestReadGzip(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "access.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(data)
+	gz.Close()
+
+	c := newCollector(agents.DefaultMatcher())
+	if err := c.read(&compressed); err != nil {
+		t.Fatal(err)
+	}
+	if c.requests != 43 {
+		t.Errorf("got %d requests, want 43", c.requests)
+	}
+}
+
+// TestReadLongLine checks that a line longer than the maximum line length is
+// skipped, and the lines after it are read.
+func TestReadLongLine(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "access.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := `192.0.2.1 - - [10/Oct/2023:13:55:36 +0000] "GET /` + strings.Repeat("a", 2*accesslog.DefaultMaxLineLength) + ` HTTP/1.1" 200 2326 "-" "curl/8.4.0"` + "\n"
+
+	c := newCollector(agents.DefaultMatcher())
+	if err := c.read(strings.NewReader(long + string(data))); err != nil {
+		t.Fatal(err)
+	}
+	if c.requests != 43 {
+		t.Errorf("got %d requests, want 43", c.requests)
+	}
+}
+
+func contains(indices []int, index int) bool {
+	for _, i := range indices {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
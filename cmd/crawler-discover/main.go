@@ -0,0 +1,121 @@
+// crawler-discover scans access logs in the Combined Log Format for User
+// Agents which are not matched by the crawler list but look like bots: they
+// contain tokens like bot, crawler or spider, a URL, do not have the structure
+// of a browser User Agent or make many requests per minute. Candidates are
+// grouped by product token and written to stdout as entries in the format of
+// crawler-user-agents.json, with their instances, to be reviewed and completed
+// before being added to the list. The evidence of each entry is reported on
+// stderr.
+//
+//	crawler-discover /var/log/nginx/access.log* > candidates.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	agents "github.com/monperrus/crawler-user-agents"
+	"github.com/monperrus/crawler-user-agents/internal/accesslog"
+	"github.com/monperrus/crawler-user-agents/internal/jsonformat"
+)
+
+// read adds the requests of r, which may be compressed with gzip or bzip2,
+// to the collector. Lines longer than accesslog.DefaultMaxLineLength are
+// skipped.
+func (c *collector) read(r io.Reader) error {
+	r, err := accesslog.Decompress(r)
+	if err != nil {
+		return err
+	}
+	lines := &accesslog.LineSplitter{Max: accesslog.DefaultMaxLineLength}
+	return lines.ReadLines(r, func(line []byte, long bool) error {
+		if long {
+			return nil
+		}
+		if rec, ok := accesslog.Parse(string(line)); ok {
+			c.add(rec)
+		}
+		return nil
+	})
+}
+
+func (c *collector) readFile(path string) error {
+	r, closer, err := accesslog.Open(path)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	if err := c.read(r); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// writeEntries writes the crawlers of the clusters as a JSON array formatted
+// like crawler-user-agents.json.
+func writeEntries(w io.Writer, clusters []*cluster, opts options) error {
+	crawlers := make([]agents.Crawler, 0, len(clusters))
+	for _, cl := range clusters {
+		crawlers = append(crawlers, cl.crawler(opts))
+	}
+	data, err := json.Marshal(crawlers)
+	if err != nil {
+		return err
+	}
+	if data, err = jsonformat.Format(data); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func main() {
+	minScore := flag.Int("min-score", 3, "minimum score of a candidate User Agent: bot token, URL and request rate count 2, non-browser structure 1")
+	minRate := flag.Float64("min-rate", 10, "requests per minute from which a User Agent is bot-like")
+	minRequests := flag.Int("min-requests", 20, "minimum number of requests of a User Agent for its request rate to count")
+	maxInstances := flag.Int("max-instances", 5, "maximum number of instances of an entry")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: crawler-discover [flags] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	c := newCollector(agents.DefaultMatcher())
+	if flag.NArg() == 0 {
+		if err := c.read(os.Stdin); err != nil {
+			fmt.Fprintln(os.Stderr, "crawler-discover:", err)
+			os.Exit(1)
+		}
+	}
+	for _, path := range flag.Args() {
+		if err := c.readFile(path); err != nil {
+			fmt.Fprintln(os.Stderr, "crawler-discover:", err)
+			os.Exit(1)
+		}
+	}
+
+	opts := options{
+		minScore:     *minScore,
+		minRate:      *minRate,
+		minRequests:  *minRequests,
+		maxInstances: *maxInstances,
+		date:         time.Now(),
+	}
+	clusters := c.clusters(opts)
+	fmt.Fprintf(os.Stderr, "crawler-discover: %d requests, %d of known crawlers, %d unknown User Agents, %d candidates\n",
+		c.requests, c.crawlers, len(c.stats), len(clusters))
+	for _, cl := range clusters {
+		fmt.Fprintf(os.Stderr, "crawler-discover: %s: %d requests from %d IPs, %d User Agents, signals: %s\n",
+			cl.product, cl.requests, cl.ips, len(cl.userAgents), strings.Join(cl.signals, ", "))
+	}
+
+	if err := writeEntries(os.Stdout, clusters, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "crawler-discover:", err)
+		os.Exit(1)
+	}
+}
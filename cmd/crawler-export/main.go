@@ -0,0 +1,265 @@
+// crawler-export writes the crawler list as configuration for web servers and
+// proxies, so that requests can be classified at the edge with the same
+// patterns as the Go package. Supported formats are an nginx map block, Apache
+// SetEnvIf or mod_rewrite rules, a HAProxy ACL pattern file and a Varnish VCL
+// subroutine. Use --tag to export only crawlers carrying one of the given tags.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+// renderer writes crawlers in one configuration format. Argument name is the
+// variable (or subroutine) that the generated configuration sets.
+type renderer func(w io.Writer, crawlers []agents.Crawler, name string) error
+
+var renderers = map[string]renderer{
+	"nginx":          renderNginx,
+	"apache":         renderApache,
+	"apache-rewrite": renderApacheRewrite,
+	"haproxy":        renderHAProxy,
+	"varnish":        renderVarnish,
+}
+
+func formatNames() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseTags parses a comma-separated list of tags.
+func parseTags(s string) (agents.TagSet, error) {
+	var tags agents.TagSet
+	if s == "" {
+		return tags, nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		tag, err := agents.ParseTag(name)
+		if err != nil {
+			return 0, err
+		}
+		tags |= agents.NewTagSet(tag)
+	}
+	return tags, nil
+}
+
+// filterByTags returns crawlers having at least one of the tags. An empty set
+// of tags selects all crawlers.
+func filterByTags(crawlers []agents.Crawler, tags agents.TagSet) []agents.Crawler {
+	if tags == 0 {
+		return crawlers
+	}
+
+	var filtered []agents.Crawler
+	for _, crawler := range crawlers {
+		if agents.NewTagSet(crawler.Tags...).HasAny(tags) {
+			filtered = append(filtered, crawler)
+		}
+	}
+	return filtered
+}
+
+// nginxQuote quotes a regexp for nginx configuration. Inside double quotes
+// nginx turns \\ into \ and \" into ", so both are escaped.
+func nginxQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+func renderNginx(w io.Writer, crawlers []agents.Crawler, name string) error {
+	fmt.Fprintf(w, "# Generated by crawler-export from crawler-user-agents.json.\n")
+	fmt.Fprintf(w, "map $http_user_agent $%s {\n", name)
+	fmt.Fprintf(w, "    default 0;\n")
+	for _, crawler := range crawlers {
+		fmt.Fprintf(w, "    %s 1;\n", nginxQuote("~"+crawler.Pattern))
+	}
+	_, err := fmt.Fprintf(w, "}\n")
+	return err
+}
+
+// escapeLeading escapes the first character of a regexp with a backslash if it
+// has a special meaning for the consumer of the regexp (e.g. negation).
+func escapeLeading(s, special string) string {
+	if s != "" && strings.ContainsRune(special, rune(s[0])) {
+		return `\` + s
+	}
+	return s
+}
+
+// apacheQuote quotes a regexp for Apache configuration. Inside double quotes
+// Apache reads \\ as \ and \" as ", so both are escaped.
+func apacheQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func renderApache(w io.Writer, crawlers []agents.Crawler, name string) error {
+	fmt.Fprintf(w, "# Generated by crawler-export from crawler-user-agents.json.\n")
+	for _, crawler := range crawlers {
+		re := escapeLeading(crawler.Pattern, "!")
+		fmt.Fprintf(w, "SetEnvIf User-Agent %s %s\n", apacheQuote(re), name)
+	}
+	return nil
+}
+
+// rewriteEscape escapes a regexp for a RewriteCond directive. mod_rewrite does
+// not support escaped quotes, so the regexp is written unquoted with escaped
+// whitespace. Leading characters introducing special conditions are escaped.
+func rewriteEscape(s string) string {
+	s = escapeLeading(s, `!-=<>"'`)
+
+	var b strings.Builder
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func renderApacheRewrite(w io.Writer, crawlers []agents.Crawler, name string) error {
+	fmt.Fprintf(w, "# Generated by crawler-export from crawler-user-agents.json.\n")
+	fmt.Fprintf(w, "RewriteEngine On\n")
+	for i, crawler := range crawlers {
+		flags := " [OR]"
+		if i == len(crawlers)-1 {
+			flags = ""
+		}
+		fmt.Fprintf(w, "RewriteCond %%{HTTP_USER_AGENT} %s%s\n", rewriteEscape(crawler.Pattern), flags)
+	}
+	if len(crawlers) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "RewriteRule ^ - [E=%s:1]\n", name)
+	return err
+}
+
+// haproxyEscape escapes a regexp for a HAProxy pattern file. HAProxy strips
+// leading whitespace and ignores lines starting with '#', so such characters
+// are escaped. Trailing whitespace is escaped as well to survive editors.
+func haproxyEscape(s string) string {
+	if s == "" {
+		return s
+	}
+
+	if s[0] == ' ' {
+		s = `\x20` + s[1:]
+	} else if s[0] == '\t' {
+		s = `\t` + s[1:]
+	} else if s[0] == '#' {
+		s = `\#` + s[1:]
+	}
+
+	last := s[len(s)-1]
+	if last == ' ' || last == '\t' {
+		// Don't touch it if the whitespace is escaped already.
+		backslashes := 0
+		for i := len(s) - 2; i >= 0 && s[i] == '\\'; i-- {
+			backslashes++
+		}
+		if backslashes%2 == 0 {
+			if last == ' ' {
+				s = s[:len(s)-1] + `\x20`
+			} else {
+				s = s[:len(s)-1] + `\t`
+			}
+		}
+	}
+
+	return s
+}
+
+func renderHAProxy(w io.Writer, crawlers []agents.Crawler, name string) error {
+	fmt.Fprintf(w, "# Generated by crawler-export from crawler-user-agents.json.\n")
+	fmt.Fprintf(w, "# Usage: acl %s req.hdr(user-agent) -m reg -f /path/to/this/file\n", name)
+	for _, crawler := range crawlers {
+		fmt.Fprintln(w, haproxyEscape(crawler.Pattern))
+	}
+	return nil
+}
+
+// varnishQuote quotes a regexp as a VCL string. VCL strings have no escape
+// sequences; a long string {"..."} is used if the regexp contains a quote.
+func varnishQuote(s string) (string, error) {
+	if strings.ContainsAny(s, "\n\r") {
+		return "", fmt.Errorf("regexp %q contains a newline", s)
+	}
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`, nil
+	}
+	if strings.Contains(s, `"}`) {
+		return "", fmt.Errorf("regexp %q can not be represented as a VCL string", s)
+	}
+	return `{"` + s + `"}`, nil
+}
+
+func renderVarnish(w io.Writer, crawlers []agents.Crawler, name string) error {
+	fmt.Fprintf(w, "# Generated by crawler-export from crawler-user-agents.json.\n")
+	fmt.Fprintf(w, "# Sets req.http.X-Crawler to \"1\" if the User-Agent is a crawler.\n")
+	fmt.Fprintf(w, "sub %s {\n", name)
+	fmt.Fprintf(w, "    unset req.http.X-Crawler;\n")
+	for i, crawler := range crawlers {
+		quoted, err := varnishQuote(crawler.Pattern)
+		if err != nil {
+			return err
+		}
+		keyword := "} elsif"
+		if i == 0 {
+			keyword = "if"
+		}
+		fmt.Fprintf(w, "    %s (req.http.User-Agent ~ %s) {\n", keyword, quoted)
+		fmt.Fprintf(w, "        set req.http.X-Crawler = \"1\";\n")
+	}
+	if len(crawlers) != 0 {
+		fmt.Fprintf(w, "    }\n")
+	}
+	_, err := fmt.Fprintf(w, "}\n")
+	return err
+}
+
+func main() {
+	format := flag.String("format", "nginx", "output format: "+strings.Join(formatNames(), ", "))
+	tags := flag.String("tag", "", "comma-separated list of tags; export only crawlers having one of them")
+	name := flag.String("name", "is_crawler", "name of the variable (nginx, Apache, HAProxy) or subroutine (Varnish) to define")
+	flag.Parse()
+
+	render, ok := renderers[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "crawler-export: unknown format %q, expected one of: %s\n", *format, strings.Join(formatNames(), ", "))
+		os.Exit(2)
+	}
+
+	tagSet, err := parseTags(*tags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "crawler-export:", err)
+		os.Exit(2)
+	}
+	crawlers := filterByTags(agents.Crawlers, tagSet)
+	if len(crawlers) == 0 {
+		fmt.Fprintf(os.Stderr, "crawler-export: no crawlers with tags %q\n", *tags)
+		os.Exit(1)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err = render(w, crawlers, *name)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "crawler-export:", err)
+		os.Exit(1)
+	}
+}
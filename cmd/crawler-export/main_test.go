@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+// nginxUnquote undoes quoting the way nginx reads a quoted configuration token.
+func nginxUnquote(t *testing.T, s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		t.Fatalf("token %q is not quoted", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case '"', '\'', '\\':
+				i++
+			case 't':
+				b.WriteByte('\t')
+				i++
+				continue
+			case 'r':
+				b.WriteByte('\r')
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// apacheUnquote undoes quoting the way Apache reads a quoted directive
+// argument (substring_conf): a backslash followed by a backslash or the quote
+// is removed, other backslashes are kept. The argument ends at the first
+// unescaped quote.
+func apacheUnquote(t *testing.T, s string) string {
+	if len(s) < 2 || s[0] != '"' {
+		t.Fatalf("token %q is not quoted", s)
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == '\\' || s[i+1] == '"'):
+			i++
+		case s[i] == '"':
+			if i != len(s)-1 {
+				t.Fatalf("token %q ends before its last character", s)
+			}
+			return b.String()
+		}
+		b.WriteByte(s[i])
+	}
+	t.Fatalf("token %q is not terminated", s)
+	return ""
+}
+
+// renderPatterns renders crawlers and extracts the regexps from the output,
+// as the consuming server would see them.
+func renderPatterns(t *testing.T, format string, crawlers []agents.Crawler) []string {
+	var buf bytes.Buffer
+	if err := renderers[format](&buf, crawlers, "is_crawler"); err != nil {
+		t.Fatalf("failed to render %s: %v", format, err)
+	}
+
+	var patterns []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch format {
+		case "nginx":
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, `"~`) {
+				continue
+			}
+			token := strings.TrimSuffix(line, " 1;")
+			patterns = append(patterns, strings.TrimPrefix(nginxUnquote(t, token), "~"))
+
+		case "apache":
+			if !strings.HasPrefix(line, "SetEnvIf User-Agent ") {
+				continue
+			}
+			token := strings.TrimPrefix(line, "SetEnvIf User-Agent ")
+			token = strings.TrimSuffix(token, " is_crawler")
+			patterns = append(patterns, apacheUnquote(t, token))
+
+		case "apache-rewrite":
+			if !strings.HasPrefix(line, "RewriteCond %{HTTP_USER_AGENT} ") {
+				continue
+			}
+			token := strings.TrimPrefix(line, "RewriteCond %{HTTP_USER_AGENT} ")
+			token = strings.TrimSuffix(token, " [OR]")
+			if strings.HasSuffix(token, " ") && !strings.HasSuffix(token, `\ `) {
+				t.Fatalf("unescaped trailing space in %q", line)
+			}
+			patterns = append(patterns, token)
+
+		case "haproxy":
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			patterns = append(patterns, strings.TrimLeft(line, " \t"))
+
+		case "varnish":
+			line = strings.TrimSpace(line)
+			line = strings.TrimPrefix(line, "} els")
+			if !strings.HasPrefix(line, "if (req.http.User-Agent ~ ") {
+				continue
+			}
+			token := strings.TrimPrefix(line, "if (req.http.User-Agent ~ ")
+			token = strings.TrimSuffix(token, ") {")
+			if strings.HasPrefix(token, `{"`) {
+				token = strings.TrimSuffix(strings.TrimPrefix(token, `{"`), `"}`)
+			} else {
+				token = strings.TrimSuffix(strings.TrimPrefix(token, `"`), `"`)
+			}
+			patterns = append(patterns, token)
+		}
+	}
+
+	if len(patterns) != len(crawlers) {
+		t.Fatalf("%s output contains %d patterns, want %d", format, len(patterns), len(crawlers))
+	}
+	return patterns
+}
+
+// TestRenderers checks for every format that each exported regexp behaves like
+// the Go pattern on all instances of the crawler.
+func TestRenderers(t *testing.T) {
+	for _, format := range formatNames() {
+		format := format
+
+		t.Run(format, func(t *testing.T) {
+			patterns := renderPatterns(t, format, agents.Crawlers)
+
+			for i, crawler := range agents.Crawlers {
+				want := regexp.MustCompile(crawler.Pattern)
+				got, err := regexp.Compile(patterns[i])
+				if err != nil {
+					t.Fatalf("exported regexp %q of pattern %q does not compile: %v", patterns[i], crawler.Pattern, err)
+				}
+
+				for _, instance := range crawler.Instances {
+					wantLoc := want.FindStringIndex(instance)
+					gotLoc := got.FindStringIndex(instance)
+					if gotLoc == nil || wantLoc[0] != gotLoc[0] || wantLoc[1] != gotLoc[1] {
+						t.Errorf("exported regexp %q matches instance %q at %v, pattern %q matches at %v", patterns[i], instance, gotLoc, crawler.Pattern, wantLoc)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestEscaping checks escaping of characters that are special in some formats.
+func TestEscaping(t *testing.T) {
+	cases := []struct {
+		pattern string
+		match   string
+		noMatch string
+	}{
+		{`quote"bot`, `a quote"bot`, `a quotebot`},
+		{`back\\slash`, `back\slash`, `backslash`},
+		{`double\\\\slash`, `double\\slash`, `double\slash`},
+		{`ends with\\`, `ends with\`, `ends with`},
+		{` leading space`, `x leading space`, `xleading space`},
+		{`trailing space `, `trailing space x`, `trailing spacex`},
+		{`#hash`, `a #hash`, `a hash`},
+		{`!bang`, `!bang`, `bang`},
+		{`-dash`, `-dash`, `dash`},
+		{`tab\tbot`, "tab\tbot", "tab bot"},
+	}
+
+	crawlers := make([]agents.Crawler, len(cases))
+	for i, tc := range cases {
+		crawlers[i] = agents.Crawler{Pattern: tc.pattern, Instances: []string{tc.match}}
+	}
+
+	for _, format := range formatNames() {
+		format := format
+
+		t.Run(format, func(t *testing.T) {
+			patterns := renderPatterns(t, format, crawlers)
+			for i, tc := range cases {
+				re, err := regexp.Compile(patterns[i])
+				if err != nil {
+					t.Fatalf("exported regexp %q of pattern %q does not compile: %v", patterns[i], tc.pattern, err)
+				}
+				if !re.MatchString(tc.match) {
+					t.Errorf("exported regexp %q of pattern %q does not match %q", patterns[i], tc.pattern, tc.match)
+				}
+				if re.MatchString(tc.noMatch) {
+					t.Errorf("exported regexp %q of pattern %q matches %q", patterns[i], tc.pattern, tc.noMatch)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterByTags(t *testing.T) {
+	if got := filterByTags(agents.Crawlers, 0); len(got) != len(agents.Crawlers) {
+		t.Errorf("empty filter returned %d crawlers, want %d", len(got), len(agents.Crawlers))
+	}
+
+	filtered := filterByTags(agents.Crawlers, agents.NewTagSet(agents.TagAICrawler))
+	if len(filtered) == 0 {
+		t.Fatalf("no crawlers with tag ai-crawler")
+	}
+	for _, crawler := range filtered {
+		found := false
+		for _, tag := range crawler.Tags {
+			if tag == agents.TagAICrawler {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("crawler %q has no tag ai-crawler", crawler.Pattern)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// crawler-format formats the JSON files of the repository exactly as
+// format.js does, so that the format can be checked without Node.js: 2 spaces
+// of indentation in the style of JSON.stringify, keeping the order of keys.
+//
+// Check the files (by default the JSON files checked by format.js) and exit
+// with status 1 if one is not formatted:
+//
+//	crawler-format -check
+//
+// Rewrite the files:
+//
+//	crawler-format -write
+//
+// Without -check or -write, the formatted files are written to stdout.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/monperrus/crawler-user-agents/internal/jsonformat"
+)
+
+// jsonFileNames are the files formatted by format.js.
+var jsonFileNames = []string{"crawler-user-agents.json", "client-hints.json", "operators.json", "browser-user-agents.json"}
+
+func main() {
+	check := flag.Bool("check", false, "report files which are not formatted and exit with status 1")
+	write := flag.Bool("write", false, "rewrite files which are not formatted")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: crawler-format [-check | -write] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *check && *write {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = jsonFileNames
+	}
+
+	failed := false
+	for _, file := range files {
+		original, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "crawler-format:", err)
+			os.Exit(1)
+		}
+		formatted, err := jsonformat.Format(original)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "crawler-format: %s: %v\n", file, err)
+			os.Exit(1)
+		}
+
+		switch {
+		case *check:
+			if !bytes.Equal(formatted, original) {
+				fmt.Fprintf(os.Stderr, "crawler-format: JSON file %s format is wrong. Run `go run ./cmd/crawler-format -write` to update.\n", file)
+				failed = true
+			}
+		case *write:
+			if !bytes.Equal(formatted, original) {
+				if err := os.WriteFile(file, formatted, 0o644); err != nil {
+					fmt.Fprintln(os.Stderr, "crawler-format:", err)
+					os.Exit(1)
+				}
+			}
+		default:
+			os.Stdout.Write(formatted)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,92 @@
+// crawler-robots generates robots.txt groups from crawler tags and reports
+// which known crawlers an existing robots.txt blocks or allows.
+//
+// Generate a robots.txt disallowing AI crawlers:
+//
+//	crawler-robots -disallow ai-crawler > robots.txt
+//
+// Check an existing robots.txt:
+//
+//	crawler-robots -check robots.txt [-path /some/page]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func generate(disallowTags []string) {
+	policies := make([]agents.RobotsPolicy, 0, len(disallowTags))
+	for _, name := range disallowTags {
+		tag, err := agents.ParseTag(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "crawler-robots:", err)
+			os.Exit(2)
+		}
+		policies = append(policies, agents.RobotsPolicy{
+			Tag:      tag,
+			Disallow: []string{"/"},
+		})
+	}
+
+	fmt.Print(agents.GenerateRobotsTxt(agents.Crawlers, policies))
+}
+
+func check(file, path string) {
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "crawler-robots:", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	robots, err := agents.ParseRobotsTxt(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "crawler-robots:", err)
+		os.Exit(1)
+	}
+
+	blocked := 0
+	verdicts := robots.Verdicts(agents.Crawlers, path)
+	for _, verdict := range verdicts {
+		status := "allowed"
+		if !verdict.Allowed {
+			status = "blocked"
+			blocked++
+		}
+		group := verdict.Group
+		if group == "" {
+			group = "-"
+		}
+		fmt.Printf("%s\t%s\tgroup=%s\tpattern=%s\n", status, verdict.Token, group, agents.Crawlers[verdict.Index].Pattern)
+	}
+	fmt.Fprintf(os.Stderr, "crawler-robots: %d of %d crawlers are blocked from %s\n", blocked, len(verdicts), path)
+}
+
+func main() {
+	disallow := flag.String("disallow", "", "comma-separated list of tags; generate robots.txt disallowing crawlers having them")
+	checkFile := flag.String("check", "", "robots.txt file to check against known crawlers")
+	path := flag.String("path", "/", "path to check with --check")
+	flag.Parse()
+
+	switch {
+	case *checkFile != "":
+		check(*checkFile, *path)
+	case *disallow != "":
+		generate(splitList(*disallow))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,265 @@
+// crawler-server exposes crawler classification over HTTP, for services which
+// can't use the Go package directly.
+//
+//	GET  /classify?ua=...       classify one User Agent
+//	POST /classify              classify a JSON array of User Agents
+//	GET  /crawlers?tag=...      list crawlers, optionally having one of the tags
+//	GET  /healthz               health check
+//	GET  /metrics               classification metrics in Prometheus format
+//
+// With --crawlers the list is read from a file instead of the embedded one.
+// The file is reloaded on SIGHUP and, with --reload-interval, when it changes.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+type server struct {
+	matcher  atomic.Pointer[agents.Matcher]
+	metrics  *agents.Metrics
+	maxBody  int64
+	maxBatch int
+}
+
+func newServer(maxBody int64, maxBatch int, maxPatternLabels int) *server {
+	s := &server{
+		metrics:  agents.NewMetrics(maxPatternLabels),
+		maxBody:  maxBody,
+		maxBatch: maxBatch,
+	}
+	s.matcher.Store(agents.DefaultMatcher())
+	return s
+}
+
+// reload replaces the list of crawlers with the contents of the file. On error
+// the current list is kept.
+func (s *server) reload(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	crawlers, err := agents.LoadCrawlers(f)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	m, err := agents.NewMatcher(crawlers)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	s.matcher.Store(m)
+	return nil
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/classify", s.handleClassify)
+	mux.HandleFunc("/crawlers", s.handleCrawlers)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.Handle("/metrics", s.metrics)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("crawler-server: failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func (s *server) handleClassify(w http.ResponseWriter, r *http.Request) {
+	m := s.matcher.Load()
+
+	switch r.Method {
+	case http.MethodGet:
+		values, ok := r.URL.Query()["ua"]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "missing query parameter ua")
+			return
+		}
+		if int64(len(values[0])) > s.maxBody {
+			writeError(w, http.StatusRequestEntityTooLarge, "user agent is too long")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.metrics.Classify(m, values[0]))
+
+	case http.MethodPost:
+		var userAgents []string
+		body := http.MaxBytesReader(w, r.Body, s.maxBody)
+		if err := json.NewDecoder(body).Decode(&userAgents); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is larger than %d bytes", s.maxBody))
+				return
+			}
+			writeError(w, http.StatusBadRequest, "request body must be a JSON array of strings: "+err.Error())
+			return
+		}
+		if len(userAgents) > s.maxBatch {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch has %d user agents, at most %d are allowed", len(userAgents), s.maxBatch))
+			return
+		}
+
+		results := make([]agents.Result, 0, len(userAgents))
+		for _, userAgent := range userAgents {
+			results = append(results, s.metrics.Classify(m, userAgent))
+		}
+		writeJSON(w, http.StatusOK, results)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *server) handleCrawlers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var wanted agents.TagSet
+	for _, name := range r.URL.Query()["tag"] {
+		tag, err := agents.ParseTag(name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		wanted |= agents.NewTagSet(tag)
+	}
+
+	matcher := s.matcher.Load()
+	crawlers := []agents.Crawler{}
+	for i, crawler := range matcher.Crawlers() {
+		if wanted == 0 || matcher.CrawlerTags(i).HasAny(wanted) {
+			crawlers = append(crawlers, crawler)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, crawlers)
+}
+
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "ok",
+		"crawlers": len(s.matcher.Load().Crawlers()),
+	})
+}
+
+// watch reloads the file on SIGHUP and, if interval is not zero, when its
+// modification time or size changes.
+func (s *server) watch(ctx context.Context, path string, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var lastMod time.Time
+	var lastSize int64
+	if info, err := os.Stat(path); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-tick:
+			info, err := os.Stat(path)
+			if err != nil {
+				log.Printf("crawler-server: %v", err)
+				continue
+			}
+			if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+				continue
+			}
+			lastMod, lastSize = info.ModTime(), info.Size()
+		}
+
+		if err := s.reload(path); err != nil {
+			log.Printf("crawler-server: reload failed, keeping the current list: %v", err)
+			continue
+		}
+		log.Printf("crawler-server: reloaded %d crawlers from %s", len(s.matcher.Load().Crawlers()), path)
+	}
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	crawlersFile := flag.String("crawlers", "", "JSON file with the list of crawlers (default: embedded list)")
+	reloadInterval := flag.Duration("reload-interval", 0, "check --crawlers file for changes with this interval (0 disables)")
+	maxBody := flag.Int64("max-body", 1<<20, "maximum size of a request body in bytes")
+	maxBatch := flag.Int("max-batch", 1000, "maximum number of user agents in a POST /classify request")
+	maxPatternLabels := flag.Int("max-pattern-labels", 200, "maximum number of distinct crawler patterns in metrics")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "time to wait for requests in flight on shutdown")
+	flag.Parse()
+
+	s := newServer(*maxBody, *maxBatch, *maxPatternLabels)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *crawlersFile != "" {
+		if err := s.reload(*crawlersFile); err != nil {
+			log.Fatalf("crawler-server: %v", err)
+		}
+		go s.watch(ctx, *crawlersFile, *reloadInterval)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("crawler-server: listening on %s", *addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		log.Fatalf("crawler-server: %v", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("crawler-server: shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("crawler-server: shutdown: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const googlebotUA = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+
+type classifyResponse struct {
+	UserAgent string `json:"user_agent"`
+	IsCrawler bool   `json:"is_crawler"`
+	Matches   []struct {
+		Pattern string `json:"pattern"`
+		Match   struct {
+			Text string `json:"text"`
+		} `json:"match"`
+	} `json:"matches"`
+}
+
+func startServer(t *testing.T) (*server, *httptest.Server) {
+	s := newServer(1024, 3, 10)
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func decode(t *testing.T, resp *http.Response, wantStatus int, v interface{}) {
+	t.Helper()
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("got status %d, want %d; body: %s", resp.StatusCode, wantStatus, body)
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("failed to decode response %s: %v", body, err)
+	}
+}
+
+func classifyGet(t *testing.T, ts *httptest.Server, userAgent string) classifyResponse {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/classify?ua=" + url.QueryEscape(userAgent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result classifyResponse
+	decode(t, resp, http.StatusOK, &result)
+	return result
+}
+
+func TestClassifyGet(t *testing.T) {
+	_, ts := startServer(t)
+
+	result := classifyGet(t, ts, googlebotUA)
+	if !result.IsCrawler || result.UserAgent != googlebotUA {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(result.Matches) == 0 || result.Matches[0].Match.Text != "Googlebot/" {
+		t.Errorf("unexpected matches: %+v", result.Matches)
+	}
+
+	if result := classifyGet(t, ts, "Mozilla/5.0 Firefox/120.0"); result.IsCrawler || len(result.Matches) != 0 {
+		t.Errorf("browser is classified as a crawler: %+v", result)
+	}
+
+	resp, err := http.Get(ts.URL + "/classify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode(t, resp, http.StatusBadRequest, nil)
+}
+
+func TestClassifyPost(t *testing.T) {
+	_, ts := startServer(t)
+
+	resp, err := http.Post(ts.URL+"/classify", "application/json", strings.NewReader(`["`+googlebotUA+`", "Mozilla/5.0 Firefox/120.0"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []classifyResponse
+	decode(t, resp, http.StatusOK, &results)
+	if len(results) != 2 || !results[0].IsCrawler || results[1].IsCrawler {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	cases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"malformed", `{"ua": 1}`, http.StatusBadRequest},
+		{"too many", `["a", "b", "c", "d"]`, http.StatusRequestEntityTooLarge},
+		{"too large", `["` + strings.Repeat("a", 2000) + `"]`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		resp, err := http.Post(ts.URL+"/classify", "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			decode(t, resp, tc.wantStatus, nil)
+		})
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, ts.URL+"/classify", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode(t, resp, http.StatusMethodNotAllowed, nil)
+}
+
+func TestCrawlers(t *testing.T) {
+	_, ts := startServer(t)
+
+	resp, err := http.Get(ts.URL + "/crawlers?tag=ai-crawler&tag=academic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crawlers []struct {
+		Pattern string   `json:"pattern"`
+		Tags    []string `json:"tags"`
+	}
+	decode(t, resp, http.StatusOK, &crawlers)
+	if len(crawlers) == 0 {
+		t.Fatalf("no crawlers returned")
+	}
+	for _, crawler := range crawlers {
+		ok := false
+		for _, tag := range crawler.Tags {
+			ok = ok || tag == "ai-crawler" || tag == "academic"
+		}
+		if !ok {
+			t.Errorf("crawler %q has tags %v", crawler.Pattern, crawler.Tags)
+		}
+	}
+
+	resp, err = http.Get(ts.URL + "/crawlers?tag=robot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode(t, resp, http.StatusBadRequest, nil)
+}
+
+func TestHealthz(t *testing.T) {
+	_, ts := startServer(t)
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var health struct {
+		Status   string `json:"status"`
+		Crawlers int    `json:"crawlers"`
+	}
+	decode(t, resp, http.StatusOK, &health)
+	if health.Status != "ok" || health.Crawlers == 0 {
+		t.Errorf("unexpected health: %+v", health)
+	}
+}
+
+func TestReload(t *testing.T) {
+	s, ts := startServer(t)
+
+	path := filepath.Join(t.TempDir(), "crawlers.json")
+	list := `[{"pattern": "examplebot", "instances": ["examplebot/1.0"], "tags": ["seo"]}]`
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.reload(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result := classifyGet(t, ts, "examplebot/1.0"); !result.IsCrawler {
+		t.Errorf("examplebot is not a crawler after reload")
+	}
+	if result := classifyGet(t, ts, googlebotUA); result.IsCrawler {
+		t.Errorf("Googlebot is still a crawler after reload")
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"pattern": "broken re["}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.reload(path); err == nil {
+		t.Fatalf("expected an error reloading a broken list")
+	}
+	if result := classifyGet(t, ts, "examplebot/1.0"); !result.IsCrawler {
+		t.Errorf("failed reload replaced the list")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	_, ts := startServer(t)
+
+	classifyGet(t, ts, googlebotUA)
+	classifyGet(t, ts, "Mozilla/5.0 Firefox/120.0")
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`crawler_classifications_total{result="crawler"} 1`,
+		`crawler_classifications_total{result="not_crawler"} 1`,
+		`crawler_matches_by_tag_total{tag="search-engine"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+}
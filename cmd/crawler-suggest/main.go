@@ -0,0 +1,93 @@
+// crawler-suggest proposes patterns for a new crawler from example User
+// Agents, given as arguments or one per line on stdin. Each proposed pattern
+// matches all examples, matches no browser of browser-user-agents.json (or of
+// the file given with --browsers) and no instance of the known crawlers,
+// neither matches nor is matched by a known pattern, and is searched as
+// literals only. Patterns are written one per line, best first.
+//
+//	crawler-suggest 'Mozilla/5.0 (compatible; ExampleBot/1.2; +https://example.com/bot)'
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+func readInstances(args []string, stdin io.Reader) ([]string, error) {
+	if len(args) != 0 {
+		return args, nil
+	}
+	var instances []string
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			instances = append(instances, line)
+		}
+	}
+	return instances, scanner.Err()
+}
+
+func readBrowsers(path string) ([]string, error) {
+	if path == "" {
+		return agents.BrowserUserAgents, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var browsers []string
+	if err := json.Unmarshal(data, &browsers); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return browsers, nil
+}
+
+// run runs the command and returns its exit status: 0 if patterns are
+// proposed, 1 on errors, including when no pattern is found, and 2 on usage
+// errors.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("crawler-suggest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	max := flags.Int("n", 5, "maximum number of patterns to propose")
+	browsersFile := flags.String("browsers", "", "JSON array of browser User Agents to use instead of the built-in corpus")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: crawler-suggest [flags] [user-agent ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	instances, err := readInstances(flags.Args(), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "crawler-suggest:", err)
+		return 1
+	}
+	browsers, err := readBrowsers(*browsersFile)
+	if err != nil {
+		fmt.Fprintln(stderr, "crawler-suggest:", err)
+		return 1
+	}
+
+	patterns, err := agents.DefaultMatcher().SuggestPatterns(instances, browsers, *max)
+	if err != nil {
+		fmt.Fprintln(stderr, "crawler-suggest:", err)
+		return 1
+	}
+	for _, pattern := range patterns {
+		fmt.Fprintln(stdout, pattern)
+	}
+	return 0
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	browsers := filepath.Join(t.TempDir(), "browsers.json")
+	if err := os.WriteFile(browsers, []byte(`["Mozilla/5.0 (compatible; ExampleBot/1.2)"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		args       []string
+		stdin      string
+		want       int
+		wantOutput string
+	}{
+		{"arguments", []string{"-n", "2", "Mozilla/5.0 (compatible; ExampleBot/1.2; +https://example.com/bot)", "ExampleBot/1.3"}, "", 0, "ExampleBot\nExampleBot\\/1\n"},
+		{"stdin", []string{"-n", "1"}, "\nExampleBot/1.3 (+https://example.com/bot)\n", 0, "ExampleBot\n"},
+		{"browsers", []string{"-n", "1", "-browsers", browsers, "ExampleBot/1.3 (+https://example.com/bot)"}, "", 0, "ExampleBot\\/1\\.3\n"},
+		{"known crawler", []string{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"}, "", 1, ""},
+		{"no instances", nil, "", 1, ""},
+		{"missing browsers", []string{"-browsers", filepath.Join(t.TempDir(), "missing.json"), "ExampleBot/1.3"}, "", 1, ""},
+		{"unknown flag", []string{"-x", "ExampleBot/1.3"}, "", 2, ""},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr); got != tc.want {
+				t.Errorf("exit status = %d, want %d (stderr %q)", got, tc.want, stderr.String())
+			}
+			if got := stdout.String(); got != tc.wantOutput {
+				t.Errorf("output %q, want %q", got, tc.wantOutput)
+			}
+			if tc.want != 0 && stderr.Len() == 0 {
+				t.Error("no error message")
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// crawler-ua tells whether User Agents are crawlers. User Agents are taken from
+// the arguments or, if there are none, from stdin (one per line). For every
+// User Agent it prints all matching crawlers with their pattern, URL,
+// description, tags, addition date and the matched part of the User Agent.
+//
+// Exit status is 0 if all User Agents are crawlers, 1 if at least one is not
+// and 2 on errors, including when there are no User Agents, so it can be used
+// in shell conditions:
+//
+//	if crawler-ua -q "$ua"; then echo bot; fi
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	agents "github.com/monperrus/crawler-user-agents"
+)
+
+const (
+	exitCrawler    = 0
+	exitNotCrawler = 1
+	exitError      = 2
+)
+
+func additionDate(crawler *agents.Crawler) string {
+	if crawler.AdditionDate.IsZero() {
+		return ""
+	}
+	return crawler.AdditionDate.Format("2006/01/02")
+}
+
+func printHuman(w io.Writer, result agents.Result) {
+	fmt.Fprintln(w, result.UserAgent)
+	if !result.IsCrawler() {
+		fmt.Fprintln(w, "  crawler: no")
+		fmt.Fprintln(w)
+		return
+	}
+
+	fmt.Fprintln(w, "  crawler: yes")
+	for _, match := range result.Matches {
+		crawler := match.Crawler
+		fmt.Fprintf(w, "  - pattern:     %s\n", crawler.Pattern)
+		fmt.Fprintf(w, "    matched:     %q at %d-%d\n", result.UserAgent[match.Start:match.End], match.Start, match.End)
+		if match.Version != "" {
+			fmt.Fprintf(w, "    version:     %s\n", match.Version)
+		}
+		if crawler.Operator != "" {
+			fmt.Fprintf(w, "    operator:    %s\n", crawler.Operator)
+		}
+		if crawler.URL != "" {
+			fmt.Fprintf(w, "    url:         %s\n", crawler.URL)
+		}
+		if crawler.Description != "" {
+			fmt.Fprintf(w, "    description: %s\n", crawler.Description)
+		}
+		if len(crawler.Tags) != 0 {
+			names := make([]string, len(crawler.Tags))
+			for i, tag := range crawler.Tags {
+				names[i] = string(tag)
+			}
+			fmt.Fprintf(w, "    tags:        %s\n", strings.Join(names, ", "))
+		}
+		if date := additionDate(crawler); date != "" {
+			fmt.Fprintf(w, "    added:       %s\n", date)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// run runs the command and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("crawler-ua", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "human", "output format: human, json or ndjson")
+	quiet := flags.Bool("q", false, "print nothing, only set the exit status")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if *format != "human" && *format != "json" && *format != "ndjson" {
+		fmt.Fprintf(stderr, "crawler-ua: unknown format %q\n", *format)
+		return exitError
+	}
+
+	var results []agents.Result
+	if flags.NArg() != 0 {
+		for _, userAgent := range flags.Args() {
+			results = append(results, agents.Classify(userAgent))
+		}
+	} else {
+		scanner := bufio.NewScanner(stdin)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			results = append(results, agents.Classify(scanner.Text()))
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintln(stderr, "crawler-ua: read error:", err)
+			return exitError
+		}
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(stderr, "crawler-ua: no User Agents")
+		return exitError
+	}
+
+	status := exitCrawler
+	for _, result := range results {
+		if !result.IsCrawler() {
+			status = exitNotCrawler
+		}
+	}
+
+	if *quiet {
+		return status
+	}
+
+	w := bufio.NewWriter(stdout)
+	switch *format {
+	case "human":
+		for _, result := range results {
+			printHuman(w, result)
+		}
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fmt.Fprintln(stderr, "crawler-ua:", err)
+			return exitError
+		}
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, result := range results {
+			if err := enc.Encode(result); err != nil {
+				fmt.Fprintln(stderr, "crawler-ua:", err)
+				return exitError
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(stderr, "crawler-ua:", err)
+		return exitError
+	}
+	return status
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const (
+	googlebotUA = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	browserUA   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+func TestRunExitStatus(t *testing.T) {
+	cases := []struct {
+		name       string
+		args       []string
+		stdin      string
+		want       int
+		wantOutput string
+	}{
+		{"crawler", []string{googlebotUA}, "", exitCrawler, "crawler: yes"},
+		{"not crawler", []string{googlebotUA, browserUA}, "", exitNotCrawler, "crawler: no"},
+		{"stdin crawlers", nil, googlebotUA + "\ncurl/8.4.0\n", exitCrawler, "curl/8.4.0"},
+		{"stdin browser", []string{"-format", "ndjson"}, browserUA + "\n", exitNotCrawler, `"is_crawler":false`},
+		{"quiet", []string{"-q", browserUA}, "", exitNotCrawler, ""},
+		{"empty stdin", nil, "", exitError, ""},
+		{"unknown format", []string{"-format", "xml", googlebotUA}, "", exitError, ""},
+		{"unknown flag", []string{"-x", googlebotUA}, "", exitError, ""},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr); got != tc.want {
+				t.Errorf("exit status = %d, want %d (stderr %q)", got, tc.want, stderr.String())
+			}
+			if tc.wantOutput == "" && stdout.Len() != 0 {
+				t.Errorf("unexpected output %q", stdout.String())
+			}
+			if !strings.Contains(stdout.String(), tc.wantOutput) {
+				t.Errorf("output %q does not contain %q", stdout.String(), tc.wantOutput)
+			}
+			if tc.want == exitError && stderr.Len() == 0 {
+				t.Error("no error message")
+			}
+		})
+	}
+}
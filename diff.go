@@ -0,0 +1,208 @@
+package agents
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// CrawlerChange is a crawler whose pattern is in both lists compared by
+// DiffCrawlers, with different fields.
+type CrawlerChange struct {
+	Pattern string `json:"pattern"`
+
+	// JSON names of the changed fields (e.g. "tags", "instances"), in the
+	// order of crawler-user-agents.json.
+	Fields []string `json:"fields"`
+
+	// Tags of the new crawler which the old one did not have, and the
+	// reverse.
+	AddedTags   []Tag `json:"added_tags,omitempty"`
+	RemovedTags []Tag `json:"removed_tags,omitempty"`
+
+	Old Crawler `json:"old"`
+	New Crawler `json:"new"`
+}
+
+// DatasetDiff is the difference between two lists of crawlers, keyed by
+// pattern: a crawler whose pattern changed is removed and added.
+type DatasetDiff struct {
+	// Crawlers of the new list only, in its order.
+	Added []Crawler `json:"added"`
+
+	// Crawlers of the old list only, in its order.
+	Removed []Crawler `json:"removed"`
+
+	// Crawlers of both lists with different fields, in the order of the new
+	// list.
+	Changed []CrawlerChange `json:"changed"`
+}
+
+// Empty reports if the lists have the same crawlers. The order of crawlers is
+// not compared.
+func (d DatasetDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffCrawlers compares two lists of crawlers, e.g. two versions of
+// crawler-user-agents.json loaded with LoadCrawlers. Crawlers are identified
+// by their pattern; if a pattern is listed twice, its first crawler is used.
+// An error is returned if a crawler cannot be encoded in JSON.
+func DiffCrawlers(old, new []Crawler) (DatasetDiff, error) {
+	oldIndices := patternIndices(old)
+	newIndices := patternIndices(new)
+	diff := DatasetDiff{
+		Added:   []Crawler{},
+		Removed: []Crawler{},
+		Changed: []CrawlerChange{},
+	}
+
+	for i, crawler := range old {
+		if oldIndices[crawler.Pattern] != i {
+			continue
+		}
+		if _, ok := newIndices[crawler.Pattern]; !ok {
+			diff.Removed = append(diff.Removed, crawler)
+		}
+	}
+	for i, crawler := range new {
+		if newIndices[crawler.Pattern] != i {
+			continue
+		}
+		j, ok := oldIndices[crawler.Pattern]
+		if !ok {
+			diff.Added = append(diff.Added, crawler)
+			continue
+		}
+		change, ok, err := diffCrawler(old[j], crawler)
+		if err != nil {
+			return DatasetDiff{}, fmt.Errorf("pattern %q: %w", crawler.Pattern, err)
+		}
+		if ok {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	return diff, nil
+}
+
+// patternIndices maps patterns to the index of their first crawler.
+func patternIndices(crawlers []Crawler) map[string]int {
+	indices := make(map[string]int, len(crawlers))
+	for i, crawler := range crawlers {
+		if _, ok := indices[crawler.Pattern]; !ok {
+			indices[crawler.Pattern] = i
+		}
+	}
+	return indices
+}
+
+// diffCrawler compares the fields of two crawlers as written in JSON, so that
+// e.g. a nil and an empty list of tags are equal.
+func diffCrawler(old, new Crawler) (CrawlerChange, bool, error) {
+	oldFields, err := jsonFields(old)
+	if err != nil {
+		return CrawlerChange{}, false, err
+	}
+	newFields, err := jsonFields(new)
+	if err != nil {
+		return CrawlerChange{}, false, err
+	}
+
+	change := CrawlerChange{Pattern: new.Pattern, Old: old, New: new}
+	for _, field := range CrawlerFields {
+		if !bytes.Equal(oldFields[field], newFields[field]) {
+			change.Fields = append(change.Fields, field)
+		}
+	}
+	oldTags, newTags := NewTagSet(old.Tags...), NewTagSet(new.Tags...)
+	for _, tag := range new.Tags {
+		if !oldTags.Has(tag) {
+			change.AddedTags = append(change.AddedTags, tag)
+		}
+	}
+	for _, tag := range old.Tags {
+		if !newTags.Has(tag) {
+			change.RemovedTags = append(change.RemovedTags, tag)
+		}
+	}
+	return change, len(change.Fields) != 0, nil
+}
+
+func jsonFields(crawler Crawler) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(crawler)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// ClassificationChange is a User Agent matched by different crawlers in the
+// lists compared by DiffClassifications.
+type ClassificationChange struct {
+	UserAgent string `json:"user_agent"`
+
+	// Patterns of the crawlers matching the User Agent in the old and the new
+	// list, in the order of MatchingCrawlers.
+	Old []string `json:"old"`
+	New []string `json:"new"`
+}
+
+// Flipped reports if the User Agent is a crawler in one list only.
+func (c ClassificationChange) Flipped() bool {
+	return len(c.Old) == 0 != (len(c.New) == 0)
+}
+
+// DiffClassifications matches the User Agents with both Matchers and returns
+// those matched by different sets of patterns, in the order of userAgents.
+// Duplicate User Agents are reported once. The behaviour of a new version of
+// the list is checked with a corpus such as access logs, instances of both
+// lists and BrowserUserAgents.
+func DiffClassifications(old, new *Matcher, userAgents []string) []ClassificationChange {
+	changes := []ClassificationChange{}
+	seen := make(map[string]bool, len(userAgents))
+	for _, userAgent := range userAgents {
+		if seen[userAgent] {
+			continue
+		}
+		seen[userAgent] = true
+
+		oldPatterns := matchingPatterns(old, userAgent)
+		newPatterns := matchingPatterns(new, userAgent)
+		if !samePatterns(oldPatterns, newPatterns) {
+			changes = append(changes, ClassificationChange{
+				UserAgent: userAgent,
+				Old:       oldPatterns,
+				New:       newPatterns,
+			})
+		}
+	}
+	return changes
+}
+
+func matchingPatterns(m *Matcher, userAgent string) []string {
+	patterns := []string{}
+	for _, index := range m.MatchingCrawlers(userAgent) {
+		patterns = append(patterns, m.crawlers[index].Pattern)
+	}
+	return patterns
+}
+
+// samePatterns reports if the lists have the same patterns, in any order.
+func samePatterns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
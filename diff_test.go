@@ -0,0 +1,112 @@
+package agents
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadTestCrawlers(t *testing.T, data string) []Crawler {
+	t.Helper()
+	crawlers, err := LoadCrawlers(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return crawlers
+}
+
+const (
+	oldTestList = `[
+		{"pattern": "examplebot", "instances": ["examplebot/1.0"], "tags": ["search-engine", "seo"]},
+		{"pattern": "oldbot", "instances": ["oldbot/1.0"]},
+		{"pattern": "samebot", "instances": ["samebot/1.0"], "addition_date": "2020/01/02"},
+		{"pattern": "other[0-9]+bot", "instances": ["other42bot"], "url": "https://example.com"}
+	]`
+	newTestList = `[
+		{"pattern": "other[0-9]+bot", "instances": ["other42bot"], "url": "https://example.org"},
+		{"pattern": "samebot", "addition_date": "2020/01/02", "instances": ["samebot/1.0"], "tags": []},
+		{"pattern": "examplebot", "instances": ["examplebot/1.0", "examplebot/2.0"], "tags": ["seo", "ai-crawler"]},
+		{"pattern": "new-?bot", "instances": ["newbot/1.0"]},
+		{"pattern": "new-?bot", "instances": ["duplicate"]}
+	]`
+)
+
+func TestDiffCrawlers(t *testing.T) {
+	old := loadTestCrawlers(t, oldTestList)
+	new := loadTestCrawlers(t, newTestList)
+	diff, err := DiffCrawlers(old, new)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(diff.Added) != 1 || diff.Added[0].Pattern != "new-?bot" || diff.Added[0].Instances[0] != "newbot/1.0" {
+		t.Errorf("Added = %+v, want the first new-?bot", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Pattern != "oldbot" {
+		t.Errorf("Removed = %+v, want oldbot", diff.Removed)
+	}
+
+	want := []CrawlerChange{
+		{
+			Pattern: "other[0-9]+bot",
+			Fields:  []string{"url"},
+			Old:     old[3],
+			New:     new[0],
+		},
+		{
+			Pattern:     "examplebot",
+			Fields:      []string{"instances", "tags"},
+			AddedTags:   []Tag{TagAICrawler},
+			RemovedTags: []Tag{TagSearchEngine},
+			Old:         old[0],
+			New:         new[2],
+		},
+	}
+	if !reflect.DeepEqual(diff.Changed, want) {
+		t.Errorf("Changed = %+v, want %+v", diff.Changed, want)
+	}
+	if diff.Empty() {
+		t.Error("diff is empty")
+	}
+
+	if diff, err := DiffCrawlers(old, old); err != nil || !diff.Empty() {
+		t.Errorf("diff of a list with itself = %+v, %v", diff, err)
+	}
+	if diff, err := DiffCrawlers(Crawlers, Crawlers); err != nil || !diff.Empty() {
+		t.Errorf("diff of Crawlers with itself is not empty: %v", err)
+	}
+}
+
+func TestDiffClassifications(t *testing.T) {
+	old, err := NewMatcher(loadTestCrawlers(t, oldTestList))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	new, err := NewMatcher(loadTestCrawlers(t, `[
+		{"pattern": "examplebot", "instances": ["examplebot/1.0"]},
+		{"pattern": "\\/2\\.0", "instances": ["examplebot/2.0"]},
+		{"pattern": "newbot", "instances": ["newbot/1.0"]},
+		{"pattern": "samebot", "instances": ["samebot/1.0"]}
+	]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	userAgents := []string{"examplebot/2.0", "oldbot/1.0", "samebot/1.0", "newbot/1.0", browserUA, "oldbot/1.0"}
+	got := DiffClassifications(old, new, userAgents)
+	want := []ClassificationChange{
+		{UserAgent: "examplebot/2.0", Old: []string{"examplebot"}, New: []string{"examplebot", `\/2\.0`}},
+		{UserAgent: "oldbot/1.0", Old: []string{"oldbot"}, New: []string{}},
+		{UserAgent: "newbot/1.0", Old: []string{}, New: []string{"newbot"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffClassifications = %+v, want %+v", got, want)
+	}
+
+	flipped := []bool{false, true, true}
+	for i, change := range got {
+		if change.Flipped() != flipped[i] {
+			t.Errorf("%q: Flipped() = %v, want %v", change.UserAgent, change.Flipped(), flipped[i])
+		}
+	}
+}
@@ -9,10 +9,13 @@
 
 declare const crawlerUserAgents: {
 	pattern: string
+	version_pattern?: string
+	operator?: string
 	addition_date?: string
 	url?: string
 	instances: string[]
 	tags?: string[]
+	robots_tokens?: string[]
 }[]
 
 export = crawlerUserAgents;
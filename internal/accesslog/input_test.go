@@ -0,0 +1,37 @@
+package accesslog
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecompress(t *testing.T) {
+	for _, name := range []string{"access.log.gz", "access.log.bz2"} {
+		r, closer, err := Open(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := io.ReadAll(r)
+		closer.Close()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !strings.HasSuffix(string(data), "\n") || !strings.Contains(string(data), `"GET /`) {
+			t.Errorf("%s: unexpected contents %q", name, data)
+		}
+	}
+
+	r, err := Decompress(strings.NewReader("plain text\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "plain text\n" {
+		t.Errorf("plain input was changed to %q", data)
+	}
+
+	if _, err := Decompress(strings.NewReader("")); err != nil {
+		t.Errorf("unexpected error for empty input: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package accesslog
+
+import (
+	"bytes"
+	"io"
+)
+
+// DefaultMaxLineLength is the default length from which lines are too long.
+const DefaultMaxLineLength = 1024 * 1024
+
+// LineSplitter splits data into lines, without their \n or \r\n. Lines longer
+// than Max bytes are cut, so that a single line cannot exhaust memory.
+type LineSplitter struct {
+	Max     int
+	partial []byte
+	long    bool
+}
+
+// Write calls emit with each complete line of the data, keeping the rest for
+// the next write. A line longer than Max is cut to Max bytes, and long is
+// true. The line is only valid during the call.
+func (s *LineSplitter) Write(data []byte, emit func(line []byte, long bool) error) error {
+	for len(data) != 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			s.add(data)
+			return nil
+		}
+		s.add(data[:i])
+		if err := s.Flush(emit); err != nil {
+			return err
+		}
+		data = data[i+1:]
+	}
+	return nil
+}
+
+func (s *LineSplitter) add(data []byte) {
+	if s.long {
+		return
+	}
+	if room := s.Max - len(s.partial); len(data) > room {
+		data = data[:room]
+		s.long = true
+	}
+	s.partial = append(s.partial, data...)
+}
+
+// Pending reports if a line without a newline was written.
+func (s *LineSplitter) Pending() bool {
+	return len(s.partial) != 0 || s.long
+}
+
+// Flush calls emit with the line written so far, if any, e.g. a last line
+// without a newline.
+func (s *LineSplitter) Flush(emit func(line []byte, long bool) error) error {
+	line, long := bytes.TrimSuffix(s.partial, []byte{'\r'}), s.long
+	s.partial, s.long = s.partial[:0], false
+	return emit(line, long)
+}
+
+// ReadLines calls emit with each line of r, as LineSplitter.Write does, until
+// the end of r or an error of emit.
+func (s *LineSplitter) ReadLines(r io.Reader, emit func(line []byte, long bool) error) error {
+	chunk := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(chunk)
+		if err := s.Write(chunk[:n], emit); err != nil {
+			return err
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if s.Pending() {
+		return s.Flush(emit)
+	}
+	return nil
+}
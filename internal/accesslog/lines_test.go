@@ -0,0 +1,57 @@
+package accesslog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type line struct {
+	text string
+	long bool
+}
+
+func TestLineSplitter(t *testing.T) {
+	var got []line
+	emit := func(data []byte, long bool) error {
+		got = append(got, line{string(data), long})
+		return nil
+	}
+
+	s := &LineSplitter{Max: 5}
+	for _, chunk := range []string{"ab", "c\r\n\nabcd", "efgh\nab", "cdef", "\nxy"} {
+		if err := s.Write([]byte(chunk), emit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !s.Pending() {
+		t.Fatal("last line is not pending")
+	}
+	if err := s.Flush(emit); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []line{{"abc", false}, {"", false}, {"abcde", true}, {"abcde", true}, {"xy", false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got lines %+v, want %+v", got, want)
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	var got []line
+	emit := func(data []byte, long bool) error {
+		got = append(got, line{string(data), long})
+		return nil
+	}
+
+	// The long line spans several reads.
+	input := "first\n" + strings.Repeat("a", 200*1024) + "\nlast"
+	s := &LineSplitter{Max: 100 * 1024}
+	if err := s.ReadLines(strings.NewReader(input), emit); err != nil {
+		t.Fatal(err)
+	}
+	want := []line{{"first", false}, {strings.Repeat("a", 100*1024), true}, {"last", false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %d lines, want %d", len(got), len(want))
+	}
+}
@@ -0,0 +1,66 @@
+// Package accesslog reads access logs in the Combined Log Format, which may be
+// compressed with gzip or bzip2, for the commands of the repository.
+package accesslog
+
+import (
+	"strings"
+	"time"
+)
+
+// Record is the part of a request of an access log used by the commands.
+type Record struct {
+	IP        string
+	Time      time.Time
+	UserAgent string
+}
+
+const timeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// UserAgent returns the User Agent of a line in the Combined Log Format, the
+// last quoted field of the line, as is.
+func UserAgent(line string) (string, bool) {
+	// Combined Log Format ends with: "referer" "user-agent"
+	end := lastUnescapedQuote(line)
+	if end < 1 {
+		return "", false
+	}
+	start := lastUnescapedQuote(line[:end])
+	if start < 0 {
+		return "", false
+	}
+	return line[start+1 : end], true
+}
+
+// Parse parses a line in the Combined Log Format. The time is zero if it
+// cannot be parsed.
+func Parse(line string) (Record, bool) {
+	var rec Record
+	var ok bool
+	if rec.UserAgent, ok = UserAgent(line); !ok {
+		return rec, false
+	}
+
+	rec.IP, _, _ = strings.Cut(line, " ")
+	if i := strings.IndexByte(line, '['); i >= 0 {
+		if j := strings.IndexByte(line[i:], ']'); j >= 0 {
+			rec.Time, _ = time.Parse(timeLayout, line[i+1:i+j])
+		}
+	}
+	return rec, true
+}
+
+// lastUnescapedQuote returns the index of the last double quote of s which is
+// not escaped with a backslash, as Apache does for quotes in the User Agent,
+// or -1.
+func lastUnescapedQuote(s string) int {
+	for i := strings.LastIndex(s, "\""); i >= 0; i = strings.LastIndex(s[:i], "\"") {
+		backslashes := 0
+		for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+			backslashes++
+		}
+		if backslashes%2 == 0 {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,44 @@
+package accesslog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserAgent(t *testing.T) {
+	cases := []struct {
+		line string
+		want string
+		ok   bool
+	}{
+		{`1.2.3.4 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "curl/8.4.0"`, "curl/8.4.0", true},
+		{`1.2.3.4 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "a \"quoted\" agent"`, `a \"quoted\" agent`, true},
+		{`1.2.3.4 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "ends with backslash\\"`, `ends with backslash\\`, true},
+		{`no quotes at all`, "", false},
+	}
+	for _, tc := range cases {
+		got, ok := UserAgent(tc.line)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("UserAgent(%q) = %q, %v, want %q, %v", tc.line, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	rec, ok := Parse(`192.0.2.1 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "curl/8.4.0"`)
+	if !ok {
+		t.Fatal("line is not parsed")
+	}
+	want := Record{
+		IP:        "192.0.2.1",
+		Time:      time.Date(2023, 10, 10, 13, 55, 36, 0, time.UTC),
+		UserAgent: "curl/8.4.0",
+	}
+	if rec.IP != want.IP || !rec.Time.Equal(want.Time) || rec.UserAgent != want.UserAgent {
+		t.Errorf("Parse = %+v, want %+v", rec, want)
+	}
+
+	if _, ok := Parse("no quotes"); ok {
+		t.Error("line without User Agent is parsed")
+	}
+}
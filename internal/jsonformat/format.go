@@ -0,0 +1,446 @@
+// Package jsonformat formats JSON exactly as format.js does with
+// JSON.stringify(JSON.parse(data), null, 2), so that the JSON files of the
+// repository can be checked and written in their canonical format from Go.
+package jsonformat
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// value is a parsed JSON value: nil for null, bool, number, jsString,
+// []value or *object.
+type value interface{}
+
+// number keeps the float64 of a JSON number, as JavaScript does.
+type number float64
+
+// jsString is a string as UTF-16 code units, so that lone surrogates of
+// \uXXXX escapes are kept as JavaScript does.
+type jsString []uint16
+
+// object keeps the keys in the order of JavaScript objects.
+type object struct {
+	keys   []jsString
+	values map[string]value
+}
+
+// mapKey returns a map key of the string, keeping lone surrogates.
+func (s jsString) mapKey() string {
+	var b strings.Builder
+	for _, c := range s {
+		b.WriteByte(byte(c >> 8))
+		b.WriteByte(byte(c))
+	}
+	return b.String()
+}
+
+// set sets the key as JSON.parse does: a duplicate key keeps its position and
+// takes the last value.
+func (o *object) set(key jsString, v value) {
+	if _, ok := o.values[key.mapKey()]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key.mapKey()] = v
+}
+
+func (o *object) get(key jsString) value {
+	return o.values[key.mapKey()]
+}
+
+// orderedKeys returns the keys in the order of JavaScript property
+// enumeration: array index keys (e.g. "0", "12") in ascending order, then the
+// other keys in insertion order.
+func (o *object) orderedKeys() []jsString {
+	var indices, others []jsString
+	for _, key := range o.keys {
+		if isArrayIndex(string(utf16.Decode(key))) {
+			indices = append(indices, key)
+		} else {
+			others = append(others, key)
+		}
+	}
+	sort.Slice(indices, func(i, j int) bool {
+		a, _ := strconv.ParseUint(string(utf16.Decode(indices[i])), 10, 32)
+		b, _ := strconv.ParseUint(string(utf16.Decode(indices[j])), 10, 32)
+		return a < b
+	})
+	return append(indices, others...)
+}
+
+// isArrayIndex reports if the key is the canonical decimal form of an integer
+// between 0 and 2^32-2.
+func isArrayIndex(key string) bool {
+	if key == "" || len(key) > 1 && key[0] == '0' {
+		return false
+	}
+	n, err := strconv.ParseUint(key, 10, 32)
+	return err == nil && n < math.MaxUint32
+}
+
+// Format returns the data formatted as by format.js:
+// JSON.stringify(JSON.parse(data), null, 2) followed by a newline.
+func Format(data []byte) ([]byte, error) {
+	p := &parser{data: string(data)}
+	p.skipSpace()
+	v, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.data) {
+		return nil, p.errorf("unexpected data after JSON value")
+	}
+
+	var b strings.Builder
+	writeValue(&b, v, "")
+	b.WriteByte('\n')
+	return []byte(b.String()), nil
+}
+
+type parser struct {
+	data string
+	pos  int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	line := 1 + strings.Count(p.data[:p.pos], "\n")
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.data[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseValue() (value, error) {
+	if p.pos == len(p.data) {
+		return nil, p.errorf("unexpected end of JSON input")
+	}
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.parseObject()
+	case c == '[':
+		return p.parseArray()
+	case c == '"':
+		return p.parseString()
+	case c == '-' || '0' <= c && c <= '9':
+		return p.parseNumber()
+	case p.consume("true"):
+		return true, nil
+	case p.consume("false"):
+		return false, nil
+	case p.consume("null"):
+		return nil, nil
+	}
+	return nil, p.errorf("unexpected character %q", p.data[p.pos])
+}
+
+func (p *parser) parseObject() (value, error) {
+	o := &object{values: make(map[string]value)}
+	p.pos++
+	p.skipSpace()
+	if p.consume("}") {
+		return o, nil
+	}
+	for {
+		if p.pos == len(p.data) || p.data[p.pos] != '"' {
+			return nil, p.errorf("expected object key")
+		}
+		key, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(":") {
+			return nil, p.errorf("expected ':' after object key")
+		}
+		p.skipSpace()
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		o.set(key, v)
+		p.skipSpace()
+		if p.consume("}") {
+			return o, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected ',' or '}' in object")
+		}
+		p.skipSpace()
+	}
+}
+
+func (p *parser) parseArray() (value, error) {
+	a := []value{}
+	p.pos++
+	p.skipSpace()
+	if p.consume("]") {
+		return a, nil
+	}
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+		p.skipSpace()
+		if p.consume("]") {
+			return a, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+		p.skipSpace()
+	}
+}
+
+func (p *parser) parseString() (jsString, error) {
+	var s jsString
+	p.pos++
+	for {
+		if p.pos == len(p.data) {
+			return nil, p.errorf("unterminated string")
+		}
+		c := p.data[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return s, nil
+		case c < 0x20:
+			return nil, p.errorf("control character in string")
+		case c == '\\':
+			if err := p.parseEscape(&s); err != nil {
+				return nil, err
+			}
+		default:
+			r, size := utf8.DecodeRuneInString(p.data[p.pos:])
+			p.pos += size
+			s = append(s, utf16.Encode([]rune{r})...)
+		}
+	}
+}
+
+var escapes = map[byte]uint16{
+	'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t',
+}
+
+func (p *parser) parseEscape(s *jsString) error {
+	if p.pos+1 == len(p.data) {
+		return p.errorf("unterminated string")
+	}
+	c := p.data[p.pos+1]
+	if c == 'u' {
+		if p.pos+6 > len(p.data) {
+			return p.errorf("invalid unicode escape")
+		}
+		u, err := strconv.ParseUint(p.data[p.pos+2:p.pos+6], 16, 16)
+		if err != nil {
+			return p.errorf("invalid unicode escape")
+		}
+		*s = append(*s, uint16(u))
+		p.pos += 6
+		return nil
+	}
+	e, ok := escapes[c]
+	if !ok {
+		return p.errorf("invalid escape %q", c)
+	}
+	*s = append(*s, e)
+	p.pos += 2
+	return nil
+}
+
+func (p *parser) parseNumber() (value, error) {
+	start := p.pos
+	p.consume("-")
+	switch {
+	case p.consume("0"):
+	case p.pos < len(p.data) && '1' <= p.data[p.pos] && p.data[p.pos] <= '9':
+		p.skipDigits()
+	default:
+		return nil, p.errorf("invalid number")
+	}
+	if p.consume(".") {
+		if !p.skipDigits() {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	if p.consume("e") || p.consume("E") {
+		if !p.consume("+") {
+			p.consume("-")
+		}
+		if !p.skipDigits() {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	f, err := strconv.ParseFloat(p.data[start:p.pos], 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return nil, p.errorf("invalid number")
+	}
+	return number(f), nil
+}
+
+func (p *parser) skipDigits() bool {
+	start := p.pos
+	for p.pos < len(p.data) && '0' <= p.data[p.pos] && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	return p.pos != start
+}
+
+// writeValue writes the value as JSON.stringify(value, null, 2) does, at the
+// given indentation.
+func writeValue(b *strings.Builder, v value, indent string) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case number:
+		b.WriteString(formatNumber(float64(v)))
+	case jsString:
+		writeString(b, v)
+	case []value:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, item := range v {
+			b.WriteString(indent + "  ")
+			writeValue(b, item, indent+"  ")
+			if i != len(v)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "]")
+	case *object:
+		if len(v.keys) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		keys := v.orderedKeys()
+		for i, key := range keys {
+			b.WriteString(indent + "  ")
+			writeString(b, key)
+			b.WriteString(": ")
+			writeValue(b, v.get(key), indent+"  ")
+			if i != len(keys)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "}")
+	}
+}
+
+// writeString quotes the string as JSON.stringify does: only quotes,
+// backslashes, control characters and lone surrogates are escaped.
+func writeString(b *strings.Builder, s jsString) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			b.WriteString(`\"`)
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '\b':
+			b.WriteString(`\b`)
+		case c == '\f':
+			b.WriteString(`\f`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 0x20:
+			fmt.Fprintf(b, `\u%04x`, c)
+		case utf16.IsSurrogate(rune(c)):
+			if i+1 < len(s) {
+				if r := utf16.DecodeRune(rune(c), rune(s[i+1])); r != utf8.RuneError {
+					b.WriteRune(r)
+					i++
+					continue
+				}
+			}
+			fmt.Fprintf(b, `\u%04x`, c)
+		default:
+			b.WriteRune(rune(c))
+		}
+	}
+	b.WriteByte('"')
+}
+
+// formatNumber formats the number as JavaScript's Number.prototype.toString.
+func formatNumber(f float64) string {
+	switch {
+	case math.IsInf(f, 0) || math.IsNaN(f):
+		return "null"
+	case f == 0:
+		return "0"
+	}
+
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	// Shortest digits d1...dk and exponent n, such that f = 0.d1...dk × 10^n.
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exp, _ := strings.Cut(e, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	n, _ := strconv.Atoi(exp)
+	n++
+	k := len(digits)
+
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+
+	expSign := "+"
+	if n-1 < 0 {
+		expSign = "-"
+	}
+	exponent := strconv.Itoa(abs(n - 1))
+	if k == 1 {
+		return sign + digits + "e" + expSign + exponent
+	}
+	return sign + digits[:1] + "." + digits[1:] + "e" + expSign + exponent
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
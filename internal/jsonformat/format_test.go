@@ -0,0 +1,98 @@
+package jsonformat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatCommittedFiles(t *testing.T) {
+	for _, name := range []string{"crawler-user-agents.json", "client-hints.json", "operators.json", "browser-user-agents.json"} {
+		data, err := os.ReadFile(filepath.Join("..", "..", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, err := Format(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(formatted) != string(data) {
+			t.Errorf("%s: formatted file differs from the committed file", name)
+		}
+	}
+}
+
+// The expected outputs are those of JSON.stringify(JSON.parse(input), null, 2).
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", ` [ ] `, "[]\n"},
+		{"scalar", `"top"`, "\"top\"\n"},
+		{
+			"nested",
+			`{"a":1,"b":{"x":[1,[2,[]],{"y":{}}]},"c":[true,false,null]}`,
+			"{\n  \"a\": 1,\n  \"b\": {\n    \"x\": [\n      1,\n      [\n        2,\n        []\n      ],\n      {\n        \"y\": {}\n      }\n    ]\n  },\n  \"c\": [\n    true,\n    false,\n    null\n  ]\n}\n",
+		},
+		{"duplicate keys", `{"a":1,"b":2,"a":3}`, "{\n  \"a\": 3,\n  \"b\": 2\n}\n"},
+		{
+			"integer keys first",
+			`{"b":1,"2":2,"10":3,"01":4,"1":5,"-1":6,"4294967295":7}`,
+			"{\n  \"1\": 5,\n  \"2\": 2,\n  \"10\": 3,\n  \"b\": 1,\n  \"01\": 4,\n  \"-1\": 6,\n  \"4294967295\": 7\n}\n",
+		},
+		{
+			"numbers",
+			`[1.0, -0, 1e21, 1e-7, 0.000001, 123e-2, 123456789012345678901234, 5e-324, 12.34e5, 2.5e-5, 1E400]`,
+			"[\n  1,\n  0,\n  1e+21,\n  1e-7,\n  0.000001,\n  1.23,\n  1.2345678901234569e+23,\n  5e-324,\n  1234000,\n  0.000025,\n  null\n]\n",
+		},
+		{
+			"escapes",
+			`["a\"b\\c\/d\b\f\n\r\t", "\u0001\u001f\u007f é", "😀", "\udc00\ud800"]`,
+			"[\n  \"a\\\"b\\\\c/d\\b\\f\\n\\r\\t\",\n  \"\\u0001\\u001f\u007f é\",\n  \"😀\",\n  \"\\udc00\\ud800\"\n]\n",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Format([]byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("Format(%s) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{``, "unexpected end"},
+		{`{"a":1,}`, "expected object key"},
+		{`[1 2]`, "expected ',' or ']'"},
+		{`{"a" 1}`, "expected ':'"},
+		{"[\n01]", "line 2: expected ',' or ']'"},
+		{`["a`, "unterminated string"},
+		{`["\x"]`, "invalid escape"},
+		{`["\u12"]`, "invalid unicode escape"},
+		{"[\"a\tb\"]", "control character"},
+		{`[1.]`, "invalid number"},
+		{`[tru]`, "unexpected character"},
+		{`[] []`, "unexpected data"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := Format([]byte(tc.input))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Format(%q) error = %v, want %q", tc.input, err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package agents
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// Match describes one crawler matching a User Agent.
+type Match struct {
+	// Index of the crawler in the list of crawlers of the Matcher.
+	Index int
+
+	// The matching crawler.
+	Crawler *Crawler
+
+	// Byte offsets of the leftmost match of the pattern in the User Agent.
+	Start, End int
+
+	// Version of the crawler extracted with its version pattern, or an empty
+	// string if it has none or it did not match.
+	Version string
+}
+
+// Result is the classification of a User Agent.
+type Result struct {
+	// The classified User Agent.
+	UserAgent string
+
+	// Crawlers matching the User Agent, in the order of MatchingCrawlers.
+	Matches []Match
+}
+
+// IsCrawler reports if any crawler matched.
+func (r Result) IsCrawler() bool {
+	return len(r.Matches) != 0
+}
+
+type jsonSpan struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+type jsonMatch struct {
+	Index        int      `json:"index"`
+	Pattern      string   `json:"pattern"`
+	URL          string   `json:"url,omitempty"`
+	Operator     string   `json:"operator,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Tags         []Tag    `json:"tags,omitempty"`
+	AdditionDate string   `json:"addition_date,omitempty"`
+	Version      string   `json:"version,omitempty"`
+	Match        jsonSpan `json:"match"`
+}
+
+type jsonResult struct {
+	UserAgent string      `json:"user_agent"`
+	IsCrawler bool        `json:"is_crawler"`
+	Matches   []jsonMatch `json:"matches"`
+}
+
+// MarshalJSON encodes the result with the details of every matching crawler
+// and the matched part of the User Agent.
+func (r Result) MarshalJSON() ([]byte, error) {
+	jr := jsonResult{
+		UserAgent: r.UserAgent,
+		IsCrawler: r.IsCrawler(),
+		Matches:   make([]jsonMatch, 0, len(r.Matches)),
+	}
+	for _, match := range r.Matches {
+		jm := jsonMatch{
+			Index:       match.Index,
+			Pattern:     match.Crawler.Pattern,
+			URL:         match.Crawler.URL,
+			Operator:    match.Crawler.Operator,
+			Description: match.Crawler.Description,
+			Tags:        match.Crawler.Tags,
+			Version:     match.Version,
+			Match: jsonSpan{
+				Start: match.Start,
+				End:   match.End,
+				Text:  r.UserAgent[match.Start:match.End],
+			},
+		}
+		if !match.Crawler.AdditionDate.IsZero() {
+			jm.AdditionDate = match.Crawler.AdditionDate.Format(timeLayout)
+		}
+		jr.Matches = append(jr.Matches, jm)
+	}
+	return json.Marshal(jr)
+}
+
+// compiledPattern returns the compiled pattern of the crawler with the index.
+// Patterns are compiled on first use, since only Classify needs them.
+func (m *Matcher) compiledPattern(index int) *regexp.Regexp {
+	m.patternsOnce.Do(func() {
+		m.patterns = make([]*regexp.Regexp, len(m.crawlers))
+		for i, crawler := range m.crawlers {
+			pattern := crawler.Pattern
+			if m.caseInsensitive {
+				pattern = "(?i)" + pattern
+			}
+			m.patterns[i] = regexp.MustCompile(pattern)
+		}
+	})
+	return m.patterns[index]
+}
+
+// Classify finds all crawlers matching the User Agent, as MatchingCrawlers
+// does, and also reports where in the User Agent each pattern matched.
+func Classify(userAgent string) Result {
+	return defaultMatcher.Classify(userAgent)
+}
+
+// Classify finds all crawlers matching the User Agent, as MatchingCrawlers
+// does, and also reports where in the User Agent each pattern matched and the
+// version of each crawler having a version pattern.
+func (m *Matcher) Classify(userAgent string) Result {
+	userAgent = m.normalized(userAgent)
+	indices := m.matchingCrawlers(userAgent)
+	result := Result{
+		UserAgent: userAgent,
+		Matches:   make([]Match, 0, len(indices)),
+	}
+
+	for _, index := range indices {
+		match := Match{
+			Index:   index,
+			Crawler: &m.crawlers[index],
+		}
+		if loc := m.compiledPattern(index).FindStringIndex(userAgent); loc != nil {
+			match.Start, match.End = loc[0], loc[1]
+		}
+		if version := m.versions[index]; version != nil {
+			if sub := version.FindStringSubmatch(userAgent); sub != nil {
+				match.Version = sub[1]
+			}
+		}
+		result.Matches = append(result.Matches, match)
+	}
+
+	return result
+}
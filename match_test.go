@@ -0,0 +1,106 @@
+package agents
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	if result := Classify(browserUA); result.IsCrawler() || len(result.Matches) != 0 {
+		t.Errorf("Browser UA %q was classified as a crawler: %v.", browserUA, result.Matches)
+	}
+
+	for i, crawler := range Crawlers {
+		re := regexp.MustCompile(crawler.Pattern)
+		for _, instance := range crawler.Instances {
+			result := Classify(instance)
+			if !result.IsCrawler() {
+				t.Errorf("Instance %q is not classified as a crawler.", instance)
+				continue
+			}
+
+			var match *Match
+			for j := range result.Matches {
+				if result.Matches[j].Index == i {
+					match = &result.Matches[j]
+				}
+			}
+			if match == nil {
+				t.Errorf("Crawler %q is not in the matches of %q.", crawler.Pattern, instance)
+				continue
+			}
+
+			if match.Crawler != &Crawlers[i] {
+				t.Errorf("Match of %q points to a wrong crawler.", crawler.Pattern)
+			}
+			span := instance[match.Start:match.End]
+			if re.FindString(instance) != span {
+				t.Errorf("Match of %q in %q has span %q.", crawler.Pattern, instance, span)
+			}
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	withVersion := 0
+	for i, crawler := range Crawlers {
+		if crawler.VersionPattern == "" {
+			continue
+		}
+		withVersion++
+
+		for _, instance := range crawler.Instances {
+			found := false
+			for _, match := range Classify(instance).Matches {
+				if match.Index != i {
+					continue
+				}
+				found = true
+				if match.Version == "" {
+					t.Errorf("No version of %q extracted from %q.", crawler.Pattern, instance)
+				}
+			}
+			if !found {
+				t.Errorf("Crawler %q is not in the matches of %q.", crawler.Pattern, instance)
+			}
+		}
+	}
+	if withVersion == 0 {
+		t.Errorf("No crawler has a version pattern.")
+	}
+
+	cases := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "2.1"},
+		{"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", "2.0"},
+		{"Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; GPTBot/1.2; +https://openai.com/gptbot)", "1.2"},
+	}
+	for _, tc := range cases {
+		result := Classify(tc.userAgent)
+		if len(result.Matches) == 0 || result.Matches[0].Version != tc.want {
+			t.Errorf("Version of %q: got matches %+v, want version %q.", tc.userAgent, result.Matches, tc.want)
+		}
+	}
+}
+
+func TestVersionPatternValidation(t *testing.T) {
+	for _, versionPattern := range []string{"examplebot/[0-9.]+", "examplebot/([0-9]+)\\.([0-9]+)", "examplebot/(["} {
+		crawlers := []Crawler{{Pattern: "examplebot", VersionPattern: versionPattern}}
+		if _, err := NewMatcher(crawlers); err == nil {
+			t.Errorf("Expected an error for version pattern %q.", versionPattern)
+		}
+	}
+
+	matcher, err := NewMatcher([]Crawler{{Pattern: "examplebot", VersionPattern: "examplebot/(?:v)?([0-9.]+)"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := matcher.Classify("examplebot/v1.5").Matches[0].Version; got != "1.5" {
+		t.Errorf("Got version %q, want %q.", got, "1.5")
+	}
+	if got := matcher.Classify("examplebot").Matches[0].Version; got != "" {
+		t.Errorf("Got version %q for a User Agent without one.", got)
+	}
+}
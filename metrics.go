@@ -0,0 +1,147 @@
+package agents
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Label value used for patterns and tags beyond the label limits.
+const otherLabel = "other"
+
+// Maximum number of distinct tag label values of Metrics.
+const maxTagLabels = 32
+
+// Metrics counts classifications by result, tag and crawler pattern and exports
+// them in the Prometheus text exposition format. The number of distinct label
+// values is bounded: patterns (and tags) seen after the limit is reached are
+// counted under the label value "other".
+type Metrics struct {
+	maxPatternLabels int
+
+	mu        sync.Mutex
+	crawlers  uint64
+	others    uint64
+	byTag     map[string]uint64
+	byPattern map[string]uint64
+}
+
+// NewMetrics creates Metrics counting at most maxPatternLabels distinct
+// patterns.
+func NewMetrics(maxPatternLabels int) *Metrics {
+	return &Metrics{
+		maxPatternLabels: maxPatternLabels,
+		byTag:            map[string]uint64{},
+		byPattern:        map[string]uint64{},
+	}
+}
+
+// countLabel increments the counter of the label value in the map, using
+// "other" if the map already has limit distinct values.
+func countLabel(counters map[string]uint64, value string, limit int) {
+	if _, ok := counters[value]; !ok && len(counters) >= limit {
+		value = otherLabel
+	}
+	counters[value]++
+}
+
+// Observe records the classification result.
+func (mt *Metrics) Observe(result Result) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if !result.IsCrawler() {
+		mt.others++
+		return
+	}
+	mt.crawlers++
+
+	seenTags := map[Tag]bool{}
+	for _, match := range result.Matches {
+		countLabel(mt.byPattern, match.Crawler.Pattern, mt.maxPatternLabels)
+		for _, tag := range match.Crawler.Tags {
+			if !seenTags[tag] {
+				seenTags[tag] = true
+				countLabel(mt.byTag, string(tag), maxTagLabels)
+			}
+		}
+	}
+}
+
+// Classify classifies the User Agent with the Matcher and records the result.
+func (mt *Metrics) Classify(m *Matcher, userAgent string) Result {
+	result := m.Classify(userAgent)
+	mt.Observe(result)
+	return result
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeCounters(w io.Writer, name, help, label string, counters map[string]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+
+	values := make([]string, 0, len(counters))
+	for value := range counters {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	for _, value := range values {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", name, label, labelValueReplacer.Replace(value), counters[value])
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (mt *Metrics) WriteTo(w io.Writer) (int64, error) {
+	mt.mu.Lock()
+	results := map[string]uint64{
+		"crawler":     mt.crawlers,
+		"not_crawler": mt.others,
+	}
+	byTag := make(map[string]uint64, len(mt.byTag))
+	for k, v := range mt.byTag {
+		byTag[k] = v
+	}
+	byPattern := make(map[string]uint64, len(mt.byPattern))
+	for k, v := range mt.byPattern {
+		byPattern[k] = v
+	}
+	mt.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	writeCounters(cw, "crawler_classifications_total", "Number of classified User Agents by result.", "result", results)
+	writeCounters(cw, "crawler_matches_by_tag_total", "Number of User Agents classified as crawlers by crawler tag.", "tag", byTag)
+	writeCounters(cw, "crawler_matches_by_pattern_total", "Number of User Agents classified as crawlers by crawler pattern.", "pattern", byPattern)
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (mt *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	// An error here means the client has gone away, nothing to do.
+	_, _ = mt.WriteTo(w)
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,61 @@
+package agents
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	mt := NewMetrics(2)
+
+	mt.Classify(defaultMatcher, crawlerUA)
+	mt.Classify(defaultMatcher, "Googlebot/2.1 (+http://www.google.com/bot.html)")
+	mt.Classify(defaultMatcher, "Googlebot/2.1 (+http://www.google.com/bot.html)")
+	mt.Classify(defaultMatcher, "curl/8.0")
+	mt.Classify(defaultMatcher, browserUA)
+
+	var buf bytes.Buffer
+	if _, err := mt.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE crawler_classifications_total counter\n",
+		`crawler_classifications_total{result="crawler"} 4` + "\n",
+		`crawler_classifications_total{result="not_crawler"} 1` + "\n",
+		`crawler_matches_by_tag_total{tag="search-engine"} 3` + "\n",
+		`crawler_matches_by_tag_total{tag="http-library"} 1` + "\n",
+		`crawler_matches_by_pattern_total{pattern="Googlebot\\/"} 2` + "\n",
+		`crawler_matches_by_pattern_total{pattern="other"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, out)
+		}
+	}
+
+	if n := strings.Count(out, "crawler_matches_by_pattern_total{"); n != 3 {
+		t.Errorf("got %d pattern label values, want 3 (2 + other):\n%s", n, out)
+	}
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	mt := NewMetrics(10)
+	mt.Observe(Classify(`quoted "bot"` + "\n" + `\ examplebot`))
+
+	rec := httptest.NewRecorder()
+	mt.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `crawler_classifications_total{result="not_crawler"} 1`) {
+		t.Errorf("unexpected body:\n%s", rec.Body.String())
+	}
+
+	if got := labelValueReplacer.Replace("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("label value escaped as %q", got)
+	}
+}
@@ -0,0 +1,173 @@
+package agents
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxDecodeRounds bounds the number of decoding rounds of NormalizeUserAgent,
+// for User Agents encoded several times.
+const maxDecodeRounds = 3
+
+// NormalizeUserAgent repairs a User Agent mangled by logging or encoding, so
+// that it can be matched. It strips surrounding whitespace and quotes, decodes
+// backslash escapes (\", \\, \xHH, as written by nginx and Apache), percent
+// escapes and "+" used for spaces (e.g. in IIS logs), drops incomplete escapes
+// at the end of truncated values, turns control characters into spaces and
+// collapses runs of whitespace. Decoding is repeated for values encoded
+// several times.
+//
+// Use it as MatcherOptions.Normalize to normalize all User Agents given to
+// a Matcher.
+func NormalizeUserAgent(userAgent string) string {
+	s := userAgent
+	for round := 0; round < maxDecodeRounds; round++ {
+		before := s
+		s = stripQuotes(strings.TrimSpace(s))
+		s = decodeBackslashEscapes(s)
+		s = decodePercentEscapes(s)
+		if !strings.Contains(s, " ") && strings.Contains(s, "+") {
+			s = strings.ReplaceAll(s, "+", " ")
+		}
+		if s == before {
+			break
+		}
+	}
+	return collapseSpaces(s)
+}
+
+// stripQuotes removes quotes surrounding the whole value, including escaped
+// ones, and a dangling opening quote of a truncated value.
+func stripQuotes(s string) string {
+	for _, quote := range []string{`\"`, `"`, `'`} {
+		if len(s) >= 2*len(quote) && strings.HasPrefix(s, quote) && strings.HasSuffix(s, quote) {
+			return strings.TrimSpace(s[len(quote) : len(s)-len(quote)])
+		}
+	}
+	if strings.HasPrefix(s, `"`) && strings.Count(s, `"`) == 1 {
+		return strings.TrimSpace(s[1:])
+	}
+	return s
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// hexByte decodes the two hexadecimal digits at the start of s.
+func hexByte(s string) (byte, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	hi, ok1 := unhex(s[0])
+	lo, ok2 := unhex(s[1])
+	return hi<<4 | lo, ok1 && ok2
+}
+
+// decodeBackslashEscapes decodes \xHH, \", \\, \t, \n and \r. Other
+// backslashes are kept, and an incomplete escape at the end is dropped.
+func decodeBackslashEscapes(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		rest := s[i+1:]
+		switch {
+		case rest == "":
+			// Truncated escape.
+		case rest[0] == 'x' && len(rest) >= 3:
+			if c, ok := hexByte(rest[1:]); ok {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+			b.WriteByte('\\')
+		case rest[0] == 'x' && len(rest) < 3 && isHexTail(rest[1:]):
+			// Truncated \xH.
+			i = len(s)
+		case rest[0] == '"', rest[0] == '\\', rest[0] == '\'':
+			b.WriteByte(rest[0])
+			i++
+		case rest[0] == 't', rest[0] == 'n', rest[0] == 'r':
+			b.WriteByte(' ')
+			i++
+		default:
+			b.WriteByte('\\')
+		}
+	}
+	return b.String()
+}
+
+// isHexTail reports if s only contains hexadecimal digits.
+func isHexTail(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if _, ok := unhex(s[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// decodePercentEscapes decodes %HH sequences. Unlike url.PathUnescape it
+// keeps invalid sequences, as User Agents may contain "%" themselves, but
+// drops an incomplete sequence at the end.
+func decodePercentEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if c, ok := hexByte(s[i+1:]); ok {
+			b.WriteByte(c)
+			i += 2
+			continue
+		}
+		if len(s)-i <= 2 && isHexTail(s[i+1:]) {
+			// Truncated %H.
+			break
+		}
+		b.WriteByte('%')
+	}
+	return b.String()
+}
+
+// collapseSpaces turns control characters into spaces, collapses runs of
+// whitespace into one space and trims the result.
+func collapseSpaces(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			space = true
+			continue
+		}
+		if space && b.Len() != 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
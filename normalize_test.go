@@ -0,0 +1,50 @@
+package agents
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNormalizeUserAgent normalizes mangled User Agents found in logs, in
+// testdata/user-agents/mangled.json.
+func TestNormalizeUserAgent(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "user-agents", "mangled.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []struct {
+		Source     string `json:"source"`
+		Input      string `json:"input"`
+		Normalized string `json:"normalized"`
+		Crawler    bool   `json:"crawler"`
+	}
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatal(err)
+	}
+
+	matcher, err := NewMatcherWithOptions(Crawlers, MatcherOptions{Normalize: NormalizeUserAgent})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.Source, func(t *testing.T) {
+			got := NormalizeUserAgent(tc.Input)
+			if got != tc.Normalized {
+				t.Errorf("NormalizeUserAgent(%q) = %q, want %q", tc.Input, got, tc.Normalized)
+			}
+			if again := NormalizeUserAgent(got); again != got {
+				t.Errorf("normalizing again changed %q to %q", got, again)
+			}
+			if isCrawler := matcher.IsCrawler(tc.Input); isCrawler != tc.Crawler {
+				t.Errorf("IsCrawler(%q) = %v, want %v", tc.Input, isCrawler, tc.Crawler)
+			}
+			if result := matcher.Classify(tc.Input); result.UserAgent != tc.Normalized || result.IsCrawler() != tc.Crawler {
+				t.Errorf("unexpected classification %+v", result)
+			}
+		})
+	}
+}
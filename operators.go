@@ -0,0 +1,100 @@
+package agents
+
+import (
+	_ "embed"
+	"encoding/json"
+)
+
+//go:embed operators.json
+var operatorsJson []byte
+
+// Operator is an organisation running crawlers, e.g. Google for Googlebot,
+// AdsBot-Google and Mediapartners-Google.
+type Operator struct {
+	// Identifier used in the operator field of crawlers (e.g. "google").
+	ID string `json:"id"`
+
+	// Display name.
+	Name string `json:"name"`
+
+	// Documentation of the crawlers of the operator.
+	URL string `json:"url,omitempty"`
+}
+
+// The controlled list of operators, built from contents of operators.json.
+var Operators = func() []Operator {
+	var operators []Operator
+	if err := json.Unmarshal(operatorsJson, &operators); err != nil {
+		panic(err)
+	}
+	return operators
+}()
+
+// LookupOperator returns the operator with the identifier.
+func LookupOperator(id string) (Operator, bool) {
+	for _, operator := range Operators {
+		if operator.ID == id {
+			return operator, true
+		}
+	}
+	return Operator{}, false
+}
+
+// CrawlersByOperator returns the indices in Crawlers of the crawlers of the
+// operator.
+func CrawlersByOperator(operator string) []int {
+	return defaultMatcher.CrawlersByOperator(operator)
+}
+
+// CrawlersByOperator returns the indices of the crawlers of the operator.
+func (m *Matcher) CrawlersByOperator(operator string) []int {
+	var indices []int
+	for i, crawler := range m.crawlers {
+		if crawler.Operator == operator {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// MatchingOperators rolls up MatchingCrawlers to operators: it returns the
+// operators of the crawlers matching the User Agent, each once, in the order
+// of their first match. Crawlers without an operator are skipped.
+func MatchingOperators(userAgent string) []string {
+	return defaultMatcher.MatchingOperators(userAgent)
+}
+
+// MatchingOperators rolls up MatchingCrawlers to operators. See the package
+// level MatchingOperators.
+func (m *Matcher) MatchingOperators(userAgent string) []string {
+	indices := m.MatchingCrawlers(userAgent)
+	operators := make([]string, 0, len(indices))
+	for _, index := range indices {
+		operators = appendOperator(operators, m.crawlers[index].Operator)
+	}
+	return operators
+}
+
+// Operators returns the operators of the matching crawlers, each once, in the
+// order of matches.
+func (r Result) Operators() []string {
+	operators := make([]string, 0, len(r.Matches))
+	for _, match := range r.Matches {
+		operators = appendOperator(operators, match.Crawler.Operator)
+	}
+	return operators
+}
+
+// appendOperator appends the operator if it is not empty and not in the list
+// yet.
+func appendOperator(operators []string, operator string) []string {
+	if operator == "" {
+		return operators
+	}
+	for _, o := range operators {
+		if o == operator {
+			return operators
+		}
+	}
+	return append(operators, operator)
+}
@@ -0,0 +1,98 @@
+package agents
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOperators(t *testing.T) {
+	ids := map[string]bool{}
+	for _, operator := range Operators {
+		if operator.ID == "" || operator.Name == "" {
+			t.Errorf("Operator %+v has no id or name.", operator)
+		}
+		if ids[operator.ID] {
+			t.Errorf("Operator %q is listed twice.", operator.ID)
+		}
+		ids[operator.ID] = true
+	}
+
+	used := map[string]bool{}
+	for _, crawler := range Crawlers {
+		if crawler.Operator == "" {
+			continue
+		}
+		if !ids[crawler.Operator] {
+			t.Errorf("Pattern %q has unknown operator %q.", crawler.Pattern, crawler.Operator)
+		}
+		used[crawler.Operator] = true
+	}
+	for id := range ids {
+		if !used[id] {
+			t.Errorf("Operator %q has no crawlers.", id)
+		}
+	}
+}
+
+func TestCrawlersByOperator(t *testing.T) {
+	patterns := map[string]bool{}
+	for _, index := range CrawlersByOperator("google") {
+		if Crawlers[index].Operator != "google" {
+			t.Errorf("Crawler %q is not run by google.", Crawlers[index].Pattern)
+		}
+		patterns[Crawlers[index].Pattern] = true
+	}
+	for _, pattern := range []string{`Googlebot\/`, "AdsBot-Google-Mobile", "Google-InspectionTool", "Mediapartners-Google"} {
+		if !patterns[pattern] {
+			t.Errorf("Crawler %q is not in the crawlers of google.", pattern)
+		}
+	}
+
+	if indices := CrawlersByOperator("unknown"); len(indices) != 0 {
+		t.Errorf("Unknown operator has crawlers %v.", indices)
+	}
+}
+
+func TestMatchingOperators(t *testing.T) {
+	matcher, err := NewMatcher([]Crawler{
+		{Pattern: "examplebot", Operator: "example"},
+		{Pattern: "example-imagebot", Operator: "example"},
+		{Pattern: "otherbot", Operator: "other"},
+		{Pattern: "anonymousbot"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		userAgent string
+		want      []string
+	}{
+		{"examplebot/1.0 example-imagebot/1.0", []string{"example"}},
+		{"otherbot examplebot", []string{"other", "example"}},
+		{"anonymousbot otherbot", []string{"other"}},
+		{"anonymousbot", []string{}},
+		{browserUA, []string{}},
+	}
+	for _, tc := range cases {
+		if got := matcher.MatchingOperators(tc.userAgent); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("MatchingOperators(%q) = %v, want %v.", tc.userAgent, got, tc.want)
+		}
+		if got := matcher.Classify(tc.userAgent).Operators(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Classify(%q).Operators() = %v, want %v.", tc.userAgent, got, tc.want)
+		}
+	}
+
+	if got := MatchingOperators(crawlerUA); len(got) == 0 {
+		t.Errorf("No operator for %q.", crawlerUA)
+	}
+}
+
+func TestLookupOperator(t *testing.T) {
+	if operator, ok := LookupOperator("openai"); !ok || operator.Name != "OpenAI" {
+		t.Errorf("LookupOperator(openai) = %+v, %v.", operator, ok)
+	}
+	if _, ok := LookupOperator("unknown"); ok {
+		t.Errorf("Unknown operator was found.")
+	}
+}
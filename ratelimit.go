@@ -0,0 +1,228 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket budget.
+type RateLimit struct {
+	// Sustained number of requests per second.
+	Rate float64 `json:"rate"`
+
+	// Maximum number of requests in a burst.
+	Burst int `json:"burst"`
+}
+
+// RateLimitConfig defines budgets of crawlers. A crawler gets the budget of its
+// pattern, otherwise the strictest budget of its tags, otherwise the default
+// budget of crawlers. Requests not coming from crawlers are not limited.
+type RateLimitConfig struct {
+	// Default budget of crawlers. If nil, crawlers without a budget of their
+	// pattern or tags are not limited.
+	Crawlers *RateLimit `json:"crawlers,omitempty"`
+
+	// Budgets by tag.
+	Tags map[Tag]RateLimit `json:"tags,omitempty"`
+
+	// Budgets by pattern.
+	Patterns map[string]RateLimit `json:"patterns,omitempty"`
+
+	// Budget of requests failing verification (see CrawlerVerifier). If nil,
+	// they get the budget of the crawler they claim to be, but in a separate
+	// bucket.
+	Spoofed *RateLimit `json:"spoofed,omitempty"`
+}
+
+func (l RateLimit) validate(name string) error {
+	if l.Rate <= 0 || math.IsInf(l.Rate, 0) || math.IsNaN(l.Rate) {
+		return fmt.Errorf("rate limit %s: rate must be positive, got %v", name, l.Rate)
+	}
+	if l.Burst < 1 {
+		return fmt.Errorf("rate limit %s: burst must be at least 1, got %d", name, l.Burst)
+	}
+	return nil
+}
+
+// stricter reports if l allows fewer requests than other: a lower rate, or the
+// same rate with a smaller burst.
+func (l RateLimit) stricter(other RateLimit) bool {
+	return l.Rate < other.Rate || l.Rate == other.Rate && l.Burst < other.Burst
+}
+
+// LoadRateLimitConfig reads and validates a RateLimitConfig in JSON format.
+func LoadRateLimitConfig(r io.Reader) (*RateLimitConfig, error) {
+	var config RateLimitConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return nil, err
+	}
+
+	if config.Crawlers != nil {
+		if err := config.Crawlers.validate("crawlers"); err != nil {
+			return nil, err
+		}
+	}
+	if config.Spoofed != nil {
+		if err := config.Spoofed.validate("spoofed"); err != nil {
+			return nil, err
+		}
+	}
+	for tag, limit := range config.Tags {
+		if err := limit.validate(fmt.Sprintf("of tag %q", tag)); err != nil {
+			return nil, err
+		}
+	}
+	for pattern, limit := range config.Patterns {
+		if err := limit.validate(fmt.Sprintf("of pattern %q", pattern)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &config, nil
+}
+
+// budget returns the budget of the crawler, or false if it is not limited.
+func (c *RateLimitConfig) budget(crawler *Crawler) (RateLimit, bool) {
+	if limit, ok := c.Patterns[crawler.Pattern]; ok {
+		return limit, true
+	}
+
+	var strictest RateLimit
+	found := false
+	for _, tag := range crawler.Tags {
+		if limit, ok := c.Tags[tag]; ok && (!found || limit.stricter(strictest)) {
+			strictest = limit
+			found = true
+		}
+	}
+	if found {
+		return strictest, true
+	}
+
+	if c.Crawlers != nil {
+		return *c.Crawlers, true
+	}
+	return RateLimit{}, false
+}
+
+// CrawlerVerifier reports if the request really comes from the crawler, e.g.
+// by checking the IP address of the client.
+type CrawlerVerifier func(r *http.Request, crawler *Crawler) bool
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter limits requests of crawlers with token buckets. There is one
+// bucket per crawler, and a separate bucket for requests of the crawler
+// failing verification. A User Agent matching several crawlers gets the
+// strictest of their budgets, in the bucket of the crawler having it.
+type RateLimiter struct {
+	config  RateLimitConfig
+	matcher *Matcher
+	verify  CrawlerVerifier
+	now     func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewRateLimiter creates a RateLimiter. If matcher is nil, DefaultMatcher is
+// used. If verify is nil, requests are not verified.
+func NewRateLimiter(config RateLimitConfig, matcher *Matcher, verify CrawlerVerifier) *RateLimiter {
+	if matcher == nil {
+		matcher = defaultMatcher
+	}
+	return &RateLimiter{
+		config:  config,
+		matcher: matcher,
+		verify:  verify,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow reports if the request is within the budget of its crawler. If not, it
+// also returns the time until the next request will be allowed.
+func (rl *RateLimiter) Allow(r *http.Request) (bool, time.Duration) {
+	indices := rl.matcher.MatchingCrawlers(r.UserAgent())
+	if len(indices) == 0 {
+		return true, 0
+	}
+	crawler := &rl.matcher.crawlers[indices[0]]
+	var limit RateLimit
+	limited := false
+	for _, i := range indices {
+		if l, ok := rl.config.budget(&rl.matcher.crawlers[i]); ok && (!limited || l.stricter(limit)) {
+			crawler, limit, limited = &rl.matcher.crawlers[i], l, true
+		}
+	}
+
+	key := "crawler\x00" + crawler.Pattern
+	if rl.verify != nil && !rl.verify(r, crawler) {
+		key = "spoofed\x00" + crawler.Pattern
+		if rl.config.Spoofed != nil {
+			limit, limited = *rl.config.Spoofed, true
+		}
+	}
+	if !limited {
+		return true, 0
+	}
+
+	return rl.take(key, limit)
+}
+
+// take takes a token from the bucket, creating a full bucket if needed.
+func (rl *RateLimiter) take(key string, limit RateLimit) (bool, time.Duration) {
+	now := rl.now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		rl.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+// Middleware returns a handler responding with 429 Too Many Requests and
+// a Retry-After header to requests of crawlers exceeding their budget.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := rl.Allow(r)
+		if !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,157 @@
+package agents
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	googlebotUA = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	gptbotUA    = "Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; GPTBot/1.0; +https://openai.com/gptbot)"
+)
+
+func TestLoadRateLimitConfig(t *testing.T) {
+	config, err := LoadRateLimitConfig(strings.NewReader(`{
+		"crawlers": {"rate": 10, "burst": 20},
+		"tags": {"ai-crawler": {"rate": 0.5, "burst": 2}},
+		"patterns": {"Googlebot\\/": {"rate": 5, "burst": 5}},
+		"spoofed": {"rate": 0.1, "burst": 1}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Patterns[`Googlebot\/`].Burst != 5 || config.Tags["ai-crawler"].Rate != 0.5 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	for _, bad := range []string{
+		`{"crawlers": {"rate": 0, "burst": 1}}`,
+		`{"tags": {"seo": {"rate": 1, "burst": 0}}}`,
+		`{"unknown": 1}`,
+	} {
+		if _, err := LoadRateLimitConfig(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for config %s", bad)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	config := RateLimitConfig{
+		Tags:     map[Tag]RateLimit{TagAICrawler: {Rate: 0.5, Burst: 2}},
+		Patterns: map[string]RateLimit{`Googlebot\/`: {Rate: 1, Burst: 1}},
+		Spoofed:  &RateLimit{Rate: 0.1, Burst: 1},
+	}
+	verify := func(r *http.Request, crawler *Crawler) bool {
+		return r.RemoteAddr != "6.6.6.6:1234"
+	}
+	rl := NewRateLimiter(config, nil, verify)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rl.now = func() time.Time { return now }
+
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	do := func(userAgent, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("User-Agent", userAgent)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Browsers and crawlers without a budget are not limited.
+	for i := 0; i < 10; i++ {
+		if rec := do(browserUA, "1.1.1.1:1"); rec.Code != http.StatusOK {
+			t.Fatalf("browser request %d got status %d", i, rec.Code)
+		}
+		if rec := do("curl/8.0", "1.1.1.1:1"); rec.Code != http.StatusOK {
+			t.Fatalf("curl request %d got status %d", i, rec.Code)
+		}
+	}
+
+	// Tag budget: burst of 2, then one request every 2 seconds.
+	for i := 0; i < 2; i++ {
+		if rec := do(gptbotUA, "1.1.1.1:1"); rec.Code != http.StatusOK {
+			t.Fatalf("GPTBot request %d got status %d", i, rec.Code)
+		}
+	}
+	rec := do(gptbotUA, "1.1.1.1:1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Fatalf("GPTBot got status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Pattern budget, independent of other crawlers.
+	if rec := do(googlebotUA, "1.1.1.1:1"); rec.Code != http.StatusOK {
+		t.Fatalf("Googlebot got status %d", rec.Code)
+	}
+	if rec := do(googlebotUA, "1.1.1.1:1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second Googlebot request got status %d", rec.Code)
+	}
+
+	// Spoofers get a separate bucket.
+	if rec := do(googlebotUA, "6.6.6.6:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("spoofed Googlebot got status %d", rec.Code)
+	}
+	rec = do(googlebotUA, "6.6.6.6:1234")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "10" {
+		t.Fatalf("spoofed Googlebot got status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Buckets refill over time.
+	now = now.Add(2 * time.Second)
+	if rec := do(gptbotUA, "1.1.1.1:1"); rec.Code != http.StatusOK {
+		t.Fatalf("GPTBot got status %d after refill", rec.Code)
+	}
+	if rec := do(googlebotUA, "1.1.1.1:1"); rec.Code != http.StatusOK {
+		t.Fatalf("Googlebot got status %d after refill", rec.Code)
+	}
+	if rec := do(googlebotUA, "6.6.6.6:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed Googlebot got status %d after 2 seconds", rec.Code)
+	}
+}
+
+// TestRateLimiterSeveralMatches checks that a User Agent matching several
+// crawlers gets the strictest of their budgets.
+func TestRateLimiterSeveralMatches(t *testing.T) {
+	matcher, err := NewMatcher([]Crawler{
+		{Pattern: "examplebot", Instances: []string{"examplebot/1.0"}, Tags: []Tag{TagSearchEngine}},
+		{Pattern: "exampleai", Instances: []string{"examplebot/1.0 exampleai"}, Tags: []Tag{TagAICrawler}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config := RateLimitConfig{
+		Tags: map[Tag]RateLimit{
+			TagSearchEngine: {Rate: 10, Burst: 10},
+			TagAICrawler:    {Rate: 0.5, Burst: 1},
+		},
+	}
+	rl := NewRateLimiter(config, matcher, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rl.now = func() time.Time { return now }
+
+	allow := func(userAgent string) bool {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("User-Agent", userAgent)
+		ok, _ := rl.Allow(req)
+		return ok
+	}
+
+	if !allow("examplebot/1.0 exampleai") {
+		t.Fatal("first request is refused")
+	}
+	if allow("examplebot/1.0 exampleai") {
+		t.Error("second request is allowed with the budget of search engines")
+	}
+	// The bucket is the one of exampleai, not of examplebot.
+	for i := 0; i < 10; i++ {
+		if !allow("examplebot/1.0") {
+			t.Fatalf("examplebot request %d is refused", i)
+		}
+	}
+}
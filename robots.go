@@ -0,0 +1,276 @@
+package agents
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RobotsPolicy describes robots.txt rules to apply to all crawlers having Tag.
+type RobotsPolicy struct {
+	// Tag selecting the crawlers (e.g. TagAICrawler).
+	Tag Tag
+
+	// Paths to disallow (e.g. "/").
+	Disallow []string
+
+	// Paths to allow, overriding Disallow for more specific paths.
+	Allow []string
+}
+
+// GenerateRobotsTxt builds robots.txt groups from the policies. Each policy
+// produces one group listing the robots.txt product tokens of the crawlers
+// having the policy's tag. Crawlers without known product tokens can't be
+// addressed in robots.txt and are skipped.
+func GenerateRobotsTxt(crawlers []Crawler, policies []RobotsPolicy) string {
+	var b strings.Builder
+	b.WriteString("# Generated from crawler-user-agents.json.\n")
+
+	for _, policy := range policies {
+		var tokens []string
+		seen := map[string]bool{}
+		for _, crawler := range crawlers {
+			if !hasTag(crawler, policy.Tag) {
+				continue
+			}
+			for _, token := range crawler.RobotsTokens {
+				if !seen[strings.ToLower(token)] {
+					seen[strings.ToLower(token)] = true
+					tokens = append(tokens, token)
+				}
+			}
+		}
+
+		if len(tokens) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n# Crawlers tagged %q.\n", policy.Tag)
+		for _, token := range tokens {
+			fmt.Fprintf(&b, "User-agent: %s\n", token)
+		}
+		for _, path := range policy.Allow {
+			fmt.Fprintf(&b, "Allow: %s\n", path)
+		}
+		for _, path := range policy.Disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", path)
+		}
+	}
+
+	return b.String()
+}
+
+func hasTag(crawler Crawler, tag Tag) bool {
+	for _, t := range crawler.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Robots is a parsed robots.txt file.
+type Robots struct {
+	groups []robotsGroup
+}
+
+type robotsGroup struct {
+	userAgents []string
+	rules      []robotsRule
+}
+
+type robotsRule struct {
+	allow bool
+	path  string
+}
+
+// ParseRobotsTxt parses a robots.txt file following RFC 9309. Unknown lines
+// and records (e.g. Sitemap or Crawl-delay) are ignored.
+func ParseRobotsTxt(r io.Reader) (*Robots, error) {
+	robots := &Robots{}
+	var group *robotsGroup
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+
+		colon := strings.IndexByte(line, ':')
+		if colon == -1 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:colon]))
+		value := strings.TrimSpace(line[colon+1:])
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group.
+			if group == nil || len(group.rules) != 0 {
+				robots.groups = append(robots.groups, robotsGroup{})
+				group = &robots.groups[len(robots.groups)-1]
+			}
+			group.userAgents = append(group.userAgents, value)
+
+		case "allow", "disallow":
+			if group == nil {
+				// Rules outside of any group are ignored.
+				continue
+			}
+			if value == "" {
+				// Empty rule matches nothing, but still ends the
+				// list of user-agents of the group.
+				group.rules = append(group.rules, robotsRule{allow: true})
+				continue
+			}
+			group.rules = append(group.rules, robotsRule{
+				allow: key == "allow",
+				path:  value,
+			})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return robots, nil
+}
+
+// rulesFor returns the rules of all groups matching the product token and the
+// user-agent value selecting them. If no group names the token, the groups for
+// "*" are used. If there are none, it returns an empty string.
+func (r *Robots) rulesFor(token string) ([]robotsRule, string) {
+	return r.rulesForTokens([]string{token})
+}
+
+// rulesForTokens returns the rules of the groups of the first token named by
+// a group, as rulesFor. The groups for "*" are only used if no group names
+// any of the tokens.
+func (r *Robots) rulesForTokens(tokens []string) ([]robotsRule, string) {
+	for _, name := range append(append([]string(nil), tokens...), "*") {
+		var rules []robotsRule
+		found := false
+		for _, group := range r.groups {
+			for _, userAgent := range group.userAgents {
+				if strings.EqualFold(userAgent, name) {
+					rules = append(rules, group.rules...)
+					found = true
+					break
+				}
+			}
+		}
+		if found {
+			return rules, name
+		}
+	}
+
+	return nil, ""
+}
+
+// Allowed reports if the crawler with the robots.txt product token may fetch
+// the path. The most specific (longest) matching rule wins; on a tie, Allow
+// wins.
+func (r *Robots) Allowed(token, path string) bool {
+	rules, _ := r.rulesFor(token)
+	return rulesAllow(rules, path)
+}
+
+func rulesAllow(rules []robotsRule, path string) bool {
+	allowed := true
+	longest := -1
+	for _, rule := range rules {
+		if rule.path == "" || !robotsPathMatch(rule.path, path) {
+			continue
+		}
+		if len(rule.path) > longest || (len(rule.path) == longest && rule.allow) {
+			longest = len(rule.path)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// robotsPathMatch reports if path matches the rule pattern, which may contain
+// '*' wildcards and a '$' end anchor.
+func robotsPathMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		pos := strings.Index(rest, part)
+		if pos == -1 {
+			return false
+		}
+		rest = rest[pos+len(part):]
+	}
+
+	return true
+}
+
+// RobotsVerdict is the result of checking a crawler against a robots.txt
+// file.
+type RobotsVerdict struct {
+	// Index of the crawler in the list being checked.
+	Index int
+
+	// The robots.txt product token of the crawler whose group applies, or its
+	// first token if the group for "*" or no group applies.
+	Token string
+
+	// User-agent value of the group that applies to the crawler: one of its
+	// tokens, "*" or an empty string if the file has no applicable group.
+	Group string
+
+	// Whether the crawler may fetch the path.
+	Allowed bool
+}
+
+// Verdicts reports for every crawler with robots.txt product tokens whether
+// the robots.txt file allows or blocks fetching the path. The group of the
+// first token of the crawler named in the file applies, as a crawler obeys
+// the most specific group (e.g. Googlebot-Image, with tokens Googlebot-Image
+// and Googlebot, obeys a group for Googlebot if there is none for
+// Googlebot-Image); the group for "*" applies only if none of its tokens is
+// named. Crawlers without product tokens are not reported.
+func (r *Robots) Verdicts(crawlers []Crawler, path string) []RobotsVerdict {
+	var verdicts []RobotsVerdict
+	for i, crawler := range crawlers {
+		if len(crawler.RobotsTokens) == 0 {
+			continue
+		}
+		rules, group := r.rulesForTokens(crawler.RobotsTokens)
+		token := crawler.RobotsTokens[0]
+		for _, t := range crawler.RobotsTokens {
+			if strings.EqualFold(t, group) {
+				token = t
+				break
+			}
+		}
+		verdicts = append(verdicts, RobotsVerdict{
+			Index:   i,
+			Token:   token,
+			Group:   group,
+			Allowed: rulesAllow(rules, path),
+		})
+	}
+	return verdicts
+}
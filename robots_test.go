@@ -0,0 +1,199 @@
+package agents
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRobotsAllowed tests group selection and rule precedence of the
+// robots.txt parser.
+func TestRobotsAllowed(t *testing.T) {
+	const robotsTxt = `
+# Comment.
+Disallow: /ignored-outside-of-group
+
+User-agent: GPTBot
+User-agent: CCBot # trailing comment
+Disallow: /
+
+user-agent: googlebot
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+
+User-agent: *
+Disallow: /tmp/
+Sitemap: https://example.com/sitemap.xml
+
+User-agent: Googlebot
+Disallow: /drafts
+`
+
+	robots, err := ParseRobotsTxt(strings.NewReader(robotsTxt))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		token string
+		path  string
+		want  bool
+	}{
+		{"GPTBot", "/", false},
+		{"gptbot", "/page", false},
+		{"CCBot", "/page", false},
+		{"Googlebot", "/", true},
+		{"Googlebot", "/private/page", false},
+		{"Googlebot", "/private/public/page", true},
+		{"Googlebot", "/doc.pdf", false},
+		{"Googlebot", "/doc.pdf?x=1", true},
+		{"Googlebot", "/drafts/1", false},
+		{"Googlebot", "/tmp/", true},
+		{"bingbot", "/tmp/file", false},
+		{"bingbot", "/ignored-outside-of-group", true},
+	}
+
+	for _, tc := range cases {
+		if got := robots.Allowed(tc.token, tc.path); got != tc.want {
+			t.Errorf("Allowed(%q, %q) = %v, want %v", tc.token, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestRobotsPathMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish$", "/fish", true},
+		{"/fish$", "/fish/", false},
+		{"/*.php", "/index.php", true},
+		{"/*.php", "/folder/any.php?x", true},
+		{"/*.php$", "/index.php?x", false},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+	}
+
+	for _, tc := range cases {
+		if got := robotsPathMatch(tc.pattern, tc.path); got != tc.want {
+			t.Errorf("robotsPathMatch(%q, %q) = %v, want %v", tc.pattern, tc.path, got, tc.want)
+		}
+	}
+}
+
+// TestGenerateRobotsTxt generates a robots.txt disallowing AI crawlers and
+// checks with the parser that exactly the tokens of AI crawlers are blocked.
+func TestGenerateRobotsTxt(t *testing.T) {
+	generated := GenerateRobotsTxt(Crawlers, []RobotsPolicy{
+		{Tag: "ai-crawler", Disallow: []string{"/"}},
+	})
+
+	robots, err := ParseRobotsTxt(strings.NewReader(generated))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	aiTokens := map[string]bool{}
+	for _, crawler := range Crawlers {
+		if hasTag(crawler, "ai-crawler") {
+			for _, token := range crawler.RobotsTokens {
+				aiTokens[token] = true
+			}
+		}
+	}
+	if !aiTokens["GPTBot"] || !aiTokens["Google-Extended"] {
+		t.Fatalf("robots tokens GPTBot and Google-Extended must belong to AI crawlers")
+	}
+
+	verdicts := robots.Verdicts(Crawlers, "/")
+	if len(verdicts) == 0 {
+		t.Fatalf("no verdicts")
+	}
+	for _, verdict := range verdicts {
+		if verdict.Allowed == aiTokens[verdict.Token] {
+			t.Errorf("token %q of crawler %q: allowed=%v", verdict.Token, Crawlers[verdict.Index].Pattern, verdict.Allowed)
+		}
+	}
+}
+
+func TestRobotsTokens(t *testing.T) {
+	for _, crawler := range Crawlers {
+		for _, token := range crawler.RobotsTokens {
+			if token == "" || strings.ContainsAny(token, " \t*:/#") {
+				t.Errorf("Pattern %q has invalid robots.txt token %q.", crawler.Pattern, token)
+			}
+		}
+	}
+}
+
+func TestRobotsVerdictsPerCrawler(t *testing.T) {
+	crawlers := []Crawler{
+		{Pattern: "Googlebot-Image", RobotsTokens: []string{"Googlebot-Image", "Googlebot"}},
+		{Pattern: "Googlebot\\/", RobotsTokens: []string{"Googlebot"}},
+		{Pattern: "bingbot", RobotsTokens: []string{"bingbot"}},
+		{Pattern: "examplebot"},
+	}
+	cases := []struct {
+		name   string
+		robots string
+		want   []RobotsVerdict
+	}{
+		{
+			"group of a second token",
+			"User-agent: Googlebot\nDisallow: /\n",
+			[]RobotsVerdict{
+				{Index: 0, Token: "Googlebot", Group: "Googlebot", Allowed: false},
+				{Index: 1, Token: "Googlebot", Group: "Googlebot", Allowed: false},
+				{Index: 2, Token: "bingbot", Group: "", Allowed: true},
+			},
+		},
+		{
+			"first token wins",
+			"User-agent: Googlebot\nDisallow: /\n\nUser-agent: Googlebot-Image\nAllow: /\n\nUser-agent: *\nDisallow: /\n",
+			[]RobotsVerdict{
+				{Index: 0, Token: "Googlebot-Image", Group: "Googlebot-Image", Allowed: true},
+				{Index: 1, Token: "Googlebot", Group: "Googlebot", Allowed: false},
+				{Index: 2, Token: "bingbot", Group: "*", Allowed: false},
+			},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			robots, err := ParseRobotsTxt(strings.NewReader(tc.robots))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := robots.Verdicts(crawlers, "/images/a.png"); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Verdicts = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestRobotsApplebotExtended checks that a group of Applebot-Extended, Apple's
+// token to opt out of AI training, does not block the Applebot crawler.
+func TestRobotsApplebotExtended(t *testing.T) {
+	robots, err := ParseRobotsTxt(strings.NewReader("User-agent: Applebot-Extended\nDisallow: /\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found := false
+	for _, verdict := range robots.Verdicts(Crawlers, "/") {
+		if Crawlers[verdict.Index].Pattern != "Applebot" {
+			continue
+		}
+		found = true
+		if !verdict.Allowed || verdict.Group != "" {
+			t.Errorf("Applebot verdict = %+v, want allowed by no group", verdict)
+		}
+	}
+	if !found {
+		t.Fatal("no verdict for Applebot")
+	}
+}
@@ -0,0 +1,251 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// ScoreWeights configures Scorer. All values are between 0 and 1.
+type ScoreWeights struct {
+	// Confidence of a match of a crawler without weighted tags.
+	Base float64 `json:"base"`
+
+	// Confidence of a match by tag. A crawler gets the highest confidence of
+	// its tags, e.g. a Googlebot match is stronger evidence than a curl one.
+	Tags map[Tag]float64 `json:"tags"`
+
+	// How much a pattern with a short literal (e.g. "bot") lowers the
+	// confidence: 0 ignores specificity, 1 gives no confidence to a pattern
+	// with a one letter literal.
+	Specificity float64 `json:"specificity"`
+
+	// Literal length from which a pattern is fully specific.
+	SpecificLength int `json:"specific_length"`
+
+	// Multiplier of the confidence if the User Agent also looks like a real
+	// browser, e.g. a browser User Agent with a crawler-like token.
+	BrowserLike float64 `json:"browser_like"`
+
+	// Confidence added by a successful verification of the crawler (e.g. by
+	// reverse DNS), combined with the confidence of the match.
+	Verified float64 `json:"verified"`
+
+	// Multiplier of the confidence if the verification of the crawler failed.
+	Unverified float64 `json:"unverified"`
+}
+
+// DefaultScoreWeights returns the weights used by Scorer unless configured
+// otherwise.
+func DefaultScoreWeights() ScoreWeights {
+	return ScoreWeights{
+		Base: 0.8,
+		Tags: map[Tag]float64{
+			TagSearchEngine:      0.95,
+			TagAICrawler:         0.95,
+			TagSocialPreview:     0.9,
+			TagSEO:               0.9,
+			TagArchiver:          0.9,
+			TagAdvertising:       0.9,
+			TagScanner:           0.9,
+			TagAcademic:          0.9,
+			TagMonitoring:        0.85,
+			TagFeedReader:        0.85,
+			TagBrowserAutomation: 0.6,
+			TagHTTPLibrary:       0.5,
+		},
+		Specificity:    0.5,
+		SpecificLength: 8,
+		BrowserLike:    0.8,
+		Verified:       0.99,
+		Unverified:     0.2,
+	}
+}
+
+// LoadScoreWeights reads ScoreWeights in JSON format. Fields missing from the
+// file keep their values of DefaultScoreWeights, and tags missing from "tags"
+// keep their default confidence.
+func LoadScoreWeights(r io.Reader) (*ScoreWeights, error) {
+	weights := DefaultScoreWeights()
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&weights); err != nil {
+		return nil, err
+	}
+
+	values := map[string]float64{
+		"base":         weights.Base,
+		"specificity":  weights.Specificity,
+		"browser_like": weights.BrowserLike,
+		"verified":     weights.Verified,
+		"unverified":   weights.Unverified,
+	}
+	for tag, value := range weights.Tags {
+		values[fmt.Sprintf("tags[%q]", tag)] = value
+	}
+	for name, value := range values {
+		if !(value >= 0 && value <= 1) {
+			return nil, fmt.Errorf("score weight %s must be between 0 and 1, got %v", name, value)
+		}
+	}
+	if weights.SpecificLength < 1 {
+		return nil, fmt.Errorf("score weight specific_length must be at least 1, got %d", weights.SpecificLength)
+	}
+
+	return &weights, nil
+}
+
+// Factors of Confidence.
+const (
+	// Confidence of the match from the tags of the crawler, or the base one.
+	FactorTag = "tag"
+
+	// Reduction for a pattern with a short literal.
+	FactorSpecificity = "specificity"
+
+	// Reduction for a User Agent looking like a real browser.
+	FactorBrowserLike = "browser-like"
+
+	// Verification of the crawler succeeded.
+	FactorVerified = "verified"
+
+	// Verification of the crawler failed.
+	FactorUnverified = "unverified"
+)
+
+// ConfidenceFactor explains one step of the computation of a Confidence.
+type ConfidenceFactor struct {
+	// Index of the crawler in the list of crawlers of the Matcher.
+	Index int
+
+	// What was taken into account, one of the Factor constants.
+	Name string
+
+	// Human readable details.
+	Detail string
+
+	// Confidence of the match of the crawler after this factor.
+	Value float64
+}
+
+// Confidence is a score between 0 and 1 of how confident the classification
+// of a User Agent as a crawler is, with its explanation.
+type Confidence struct {
+	// Classification of the User Agent.
+	Result
+
+	// Combined confidence of all matches, 0 if no crawler matched.
+	Score float64
+
+	// Factors which led to the score, in the order they were applied.
+	Factors []ConfidenceFactor
+}
+
+// Scorer computes the Confidence of classifications.
+type Scorer struct {
+	weights ScoreWeights
+	matcher *Matcher
+}
+
+// NewScorer creates a Scorer. If matcher is nil, DefaultMatcher is used.
+func NewScorer(weights ScoreWeights, matcher *Matcher) *Scorer {
+	if matcher == nil {
+		matcher = defaultMatcher
+	}
+	return &Scorer{
+		weights: weights,
+		matcher: matcher,
+	}
+}
+
+// Score computes the confidence that the User Agent is a crawler.
+func (s *Scorer) Score(userAgent string) Confidence {
+	return s.score(s.matcher.Classify(userAgent), nil)
+}
+
+// ScoreRequest computes the confidence that the request comes from
+// a crawler, verifying each matching crawler with verify. If verify is nil, it
+// is the same as Score.
+func (s *Scorer) ScoreRequest(r *http.Request, verify CrawlerVerifier) Confidence {
+	var verified func(crawler *Crawler) bool
+	if verify != nil {
+		verified = func(crawler *Crawler) bool {
+			return verify(r, crawler)
+		}
+	}
+	return s.score(s.matcher.Classify(r.UserAgent()), verified)
+}
+
+func (s *Scorer) score(result Result, verified func(crawler *Crawler) bool) Confidence {
+	c := Confidence{Result: result}
+	w := s.weights
+	browser := browserFamily(result.UserAgent)
+
+	for _, match := range result.Matches {
+		factor := func(name string, value float64, format string, args ...interface{}) float64 {
+			c.Factors = append(c.Factors, ConfidenceFactor{
+				Index:  match.Index,
+				Name:   name,
+				Detail: fmt.Sprintf(format, args...),
+				Value:  value,
+			})
+			return value
+		}
+
+		p, tag := w.Base, Tag("")
+		for _, t := range match.Crawler.Tags {
+			if value, ok := w.Tags[t]; ok && (tag == "" || value > p) {
+				p, tag = value, t
+			}
+		}
+		if tag != "" {
+			p = factor(FactorTag, p, "pattern %q has tag %s", match.Crawler.Pattern, tag)
+		} else {
+			p = factor(FactorTag, p, "pattern %q has no weighted tag", match.Crawler.Pattern)
+		}
+
+		length := s.matcher.literalLens[match.Index]
+		if length < w.SpecificLength {
+			specificity := float64(length) / float64(w.SpecificLength)
+			p = factor(FactorSpecificity, p*(1-w.Specificity*(1-specificity)),
+				"pattern %q is searched with a %d byte literal", match.Crawler.Pattern, length)
+		}
+
+		if browser != "" {
+			p = factor(FactorBrowserLike, p*w.BrowserLike, "User Agent also looks like %s", browser)
+		}
+
+		if verified != nil {
+			if verified(match.Crawler) {
+				p = factor(FactorVerified, 1-(1-p)*(1-w.Verified), "request is verified as %q", match.Crawler.Pattern)
+			} else {
+				p = factor(FactorUnverified, p*w.Unverified, "request failed verification as %q", match.Crawler.Pattern)
+			}
+		}
+
+		// Combine matches as independent pieces of evidence.
+		c.Score = 1 - (1-c.Score)*(1-p)
+	}
+
+	c.Score = math.Min(math.Max(c.Score, 0), 1)
+	return c
+}
+
+// shortestLiteralLen returns the length of the shortest literal, not counting
+// anchors.
+func shortestLiteralLen(literals []string) int {
+	shortest := -1
+	for _, literal := range literals {
+		n := len(strings.TrimSuffix(strings.TrimPrefix(literal, "^"), "$"))
+		if shortest == -1 || n < shortest {
+			shortest = n
+		}
+	}
+	if shortest == -1 {
+		return 0
+	}
+	return shortest
+}
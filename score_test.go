@@ -0,0 +1,113 @@
+package agents
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	scorer := NewScorer(DefaultScoreWeights(), nil)
+
+	googlebot := scorer.Score(googlebotUA)
+	curl := scorer.Score("curl/8.4.0")
+	requests := scorer.Score("python-requests/2.31.0")
+	smartphone := scorer.Score("Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/41.0.2272.96 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
+	browser := scorer.Score(browserUA)
+
+	if browser.Score != 0 || len(browser.Factors) != 0 {
+		t.Errorf("browser UA has confidence %+v", browser)
+	}
+	if !(googlebot.Score > requests.Score && requests.Score > curl.Score && curl.Score > 0) {
+		t.Errorf("unexpected order of scores: Googlebot %v, python-requests %v, curl %v", googlebot.Score, requests.Score, curl.Score)
+	}
+	if !(smartphone.Score < googlebot.Score) {
+		t.Errorf("browser-like Googlebot %v is not below Googlebot %v", smartphone.Score, googlebot.Score)
+	}
+
+	for _, tc := range []struct {
+		confidence Confidence
+		factors    []string
+	}{
+		{googlebot, []string{FactorTag}},
+		{curl, []string{FactorTag, FactorSpecificity}},
+		{smartphone, []string{FactorTag, FactorBrowserLike}},
+	} {
+		var factors []string
+		for _, factor := range tc.confidence.Factors {
+			factors = append(factors, factor.Name)
+			if factor.Detail == "" || factor.Value < 0 || factor.Value > 1 {
+				t.Errorf("unexpected factor %+v", factor)
+			}
+		}
+		if strings.Join(factors, ",") != strings.Join(tc.factors, ",") {
+			t.Errorf("%q has factors %v, want %v", tc.confidence.UserAgent, factors, tc.factors)
+		}
+		if last := tc.confidence.Factors[len(tc.confidence.Factors)-1]; last.Value != tc.confidence.Score {
+			t.Errorf("%q has score %v, but the last factor is %v", tc.confidence.UserAgent, tc.confidence.Score, last.Value)
+		}
+	}
+}
+
+func TestScoreRequest(t *testing.T) {
+	scorer := NewScorer(DefaultScoreWeights(), nil)
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", googlebotUA)
+
+	plain := scorer.ScoreRequest(req, nil)
+	verified := scorer.ScoreRequest(req, func(r *http.Request, crawler *Crawler) bool { return true })
+	spoofed := scorer.ScoreRequest(req, func(r *http.Request, crawler *Crawler) bool { return false })
+
+	if plain.Score != scorer.Score(googlebotUA).Score {
+		t.Errorf("ScoreRequest without verification %v differs from Score", plain.Score)
+	}
+	if !(verified.Score > plain.Score && plain.Score > spoofed.Score) {
+		t.Errorf("unexpected scores: verified %v, plain %v, spoofed %v", verified.Score, plain.Score, spoofed.Score)
+	}
+	if last := spoofed.Factors[len(spoofed.Factors)-1]; last.Name != FactorUnverified {
+		t.Errorf("last factor of spoofed request is %+v", last)
+	}
+}
+
+func TestLoadScoreWeights(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "score-weights.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	weights, err := LoadScoreWeights(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defaults := DefaultScoreWeights()
+	if weights.Base != 0.7 || weights.Tags[TagHTTPLibrary] != 0.3 || weights.Unverified != 0.05 {
+		t.Errorf("weights of the file were not loaded: %+v", weights)
+	}
+	if weights.Tags[TagSearchEngine] != defaults.Tags[TagSearchEngine] || weights.BrowserLike != defaults.BrowserLike {
+		t.Errorf("missing weights do not keep their defaults: %+v", weights)
+	}
+
+	configured := NewScorer(*weights, nil).Score("python-requests/2.31.0")
+	if math.Abs(configured.Score-0.3) > 1e-9 {
+		t.Errorf("python-requests has score %v with configured weights, want 0.3", configured.Score)
+	}
+
+	for _, config := range []string{
+		`{"base": 1.5}`,
+		`{"tags": {"robot": 0.5}}`,
+		`{"tags": {"seo": -1}}`,
+		`{"specific_length": 0}`,
+		`{"unknown": 1}`,
+	} {
+		if _, err := LoadScoreWeights(strings.NewReader(config)); err == nil {
+			t.Errorf("expected an error for %s", config)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+//go:build go1.21
+
+package agents
+
+import (
+	"context"
+	"log/slog"
+)
+
+// UserAgentKey is the key of the slog attribute SlogHandler classifies.
+const UserAgentKey = "user_agent"
+
+// LogValue implements slog.LogValuer. It logs whether the User Agent is a bot,
+// the pattern of the first matching crawler and the tags of all matches.
+func (r Result) LogValue() slog.Value {
+	if !r.IsCrawler() {
+		return slog.GroupValue(slog.Bool("bot", false))
+	}
+
+	var tags []Tag
+	seen := map[Tag]bool{}
+	for _, match := range r.Matches {
+		for _, tag := range match.Crawler.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return slog.GroupValue(
+		slog.Bool("bot", true),
+		slog.String("crawler", r.Matches[0].Crawler.Pattern),
+		slog.Any("tags", tags),
+	)
+}
+
+// SlogHandler is a slog.Handler which adds classification attributes (bot,
+// crawler and tags, see Result.LogValue) to records carrying a "user_agent"
+// attribute, then passes them to the wrapped handler.
+//
+// The attributes are added next to the "user_agent" attribute, in the same
+// group: after WithGroup("http"), a "user_agent" attribute of a record is
+// logged as http.user_agent and its classification as http.bot,
+// http.crawler and http.tags, while a "user_agent" attribute added with
+// WithAttrs before WithGroup is classified at the top level.
+type SlogHandler struct {
+	next    slog.Handler
+	matcher *Matcher
+}
+
+// NewSlogHandler wraps the handler. If matcher is nil, DefaultMatcher is used.
+func NewSlogHandler(next slog.Handler, matcher *Matcher) *SlogHandler {
+	if matcher == nil {
+		matcher = defaultMatcher
+	}
+	return &SlogHandler{
+		next:    next,
+		matcher: matcher,
+	}
+}
+
+// classification returns the attributes to add for the attribute, if it is
+// a User Agent.
+func (h *SlogHandler) classification(attr slog.Attr) ([]slog.Attr, bool) {
+	if attr.Key != UserAgentKey {
+		return nil, false
+	}
+	value := attr.Value.Resolve()
+	if value.Kind() != slog.KindString {
+		return nil, false
+	}
+	return h.matcher.Classify(value.String()).LogValue().Group(), true
+}
+
+// Enabled implements slog.Handler.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	var extra []slog.Attr
+	record.Attrs(func(attr slog.Attr) bool {
+		var ok bool
+		extra, ok = h.classification(attr)
+		return !ok
+	})
+
+	if extra != nil {
+		record = record.Clone()
+		record.AddAttrs(extra...)
+	}
+
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	for _, attr := range attrs {
+		if extra, ok := h.classification(attr); ok {
+			attrs = append(attrs[:len(attrs):len(attrs)], extra...)
+			break
+		}
+	}
+	return &SlogHandler{
+		next:    h.next.WithAttrs(attrs),
+		matcher: h.matcher,
+	}
+}
+
+// WithGroup implements slog.Handler. Classification attributes of the
+// following records are nested in the group, with their "user_agent"
+// attribute.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	return &SlogHandler{
+		next:    h.next.WithGroup(name),
+		matcher: h.matcher,
+	}
+}
@@ -0,0 +1,93 @@
+//go:build go1.21
+
+package agents
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	text := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+	return slog.New(NewSlogHandler(text, nil))
+}
+
+func TestResultLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	logger.Info("request", "result", Classify("Googlebot/2.1 (+http://www.google.com/bot.html)"))
+	logger.Info("request", "result", Classify(browserUA))
+
+	want := `level=INFO msg=request result.bot=true result.crawler=Googlebot\/ result.tags=[search-engine]
+level=INFO msg=request result.bot=false
+`
+	if buf.String() != want {
+		t.Errorf("got log:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	logger.Info("request", "path", "/", UserAgentKey, "Googlebot/2.1 (+http://www.google.com/bot.html)")
+	logger.Info("request", "path", "/", UserAgentKey, browserUA)
+	logger.Info("request", "path", "/")
+	logger.With(UserAgentKey, "curl/8.0").Info("request", "path", "/")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4:\n%s", len(lines), buf.String())
+	}
+
+	wantSuffixes := []string{
+		` bot=true crawler=Googlebot\/ tags=[search-engine]`,
+		` bot=false`,
+		`msg=request path=/`,
+		`user_agent=curl/8.0 bot=true crawler=^curl tags=[http-library] path=/`,
+	}
+	for i, want := range wantSuffixes {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("line %q does not end with %q", lines[i], want)
+		}
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	text := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})
+	handler := NewSlogHandler(text, nil)
+
+	if handler.Enabled(context.Background(), slog.LevelInfo) {
+		t.Errorf("info level must be disabled")
+	}
+	if !handler.Enabled(context.Background(), slog.LevelError) {
+		t.Errorf("error level must be enabled")
+	}
+}
+
+func TestSlogHandlerWithGroup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	logger.WithGroup("http").Info("request", "path", "/", UserAgentKey, "curl/8.0")
+	logger.With(UserAgentKey, "curl/8.0").WithGroup("http").Info("request", "path", "/")
+
+	want := `level=INFO msg=request http.path=/ http.user_agent=curl/8.0 http.bot=true http.crawler=^curl http.tags=[http-library]
+level=INFO msg=request user_agent=curl/8.0 bot=true crawler=^curl tags=[http-library] http.path=/
+`
+	if buf.String() != want {
+		t.Errorf("got log:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
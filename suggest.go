@@ -0,0 +1,274 @@
+package agents
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed browser-user-agents.json
+var browsersJson []byte
+
+// BrowserUserAgents is an offline corpus of User Agents of browsers, built from
+// browser-user-agents.json. No crawler pattern may match any of them.
+var BrowserUserAgents = func() []string {
+	var browsers []string
+	if err := json.Unmarshal(browsersJson, &browsers); err != nil {
+		panic(err)
+	}
+	return browsers
+}()
+
+// minSuggestedLiteralLen is the length of the shortest literal suggested as
+// a pattern, the same as the main literal of a regexp pattern.
+const minSuggestedLiteralLen = 3
+
+// ErrNoPatternFound is returned by SuggestPatterns when no pattern matching
+// all instances can be built from literals of the instances.
+var ErrNoPatternFound = errors.New("no pattern matches all instances without matching browsers or other crawlers")
+
+// SuggestPatterns proposes patterns for a new crawler with the instances, best
+// first, checked against the crawlers of DefaultMatcher and BrowserUserAgents.
+// See Matcher.SuggestPatterns.
+func SuggestPatterns(instances []string, max int) ([]string, error) {
+	return defaultMatcher.SuggestPatterns(instances, BrowserUserAgents, max)
+}
+
+// SuggestPatterns proposes at most max patterns for a new crawler with the
+// instances, best first. A pattern matches all instances, matches none of
+// the browser User Agents nor any instance of the crawlers of the Matcher, and
+// is expanded by analyzePattern to literals only, so it is searched without
+// a regular expression. As for crawler-db, it is neither, ignoring case, a
+// pattern of the Matcher, nor matches one or is matched by one.
+//
+// Patterns are fragments of the instances starting with a letter at a word
+// boundary, outside of URLs. Fragments ending at a word boundary (e.g.
+// "ExampleBot" rather than "ExampleB") come first, the shortest first, and
+// other fragments are only proposed if none of them is suitable. If no
+// fragment is common to all instances, the pattern is an alternation of
+// fragments covering the instances.
+//
+// An error is returned if an instance is already matched by a crawler of the
+// Matcher.
+func (m *Matcher) SuggestPatterns(instances, browsers []string, max int) ([]string, error) {
+	if len(instances) == 0 {
+		return nil, errors.New("no instances")
+	}
+	for _, instance := range instances {
+		if indices := m.MatchingCrawlers(instance); len(indices) != 0 {
+			return nil, fmt.Errorf("instance %q is already matched by pattern %q", instance, m.crawlers[indices[0]].Pattern)
+		}
+	}
+
+	s := &suggester{matcher: m, browsers: browsers}
+	var patterns []string
+	fragments, whole := s.fragments(instances[0])
+	for i, fragment := range fragments {
+		if len(patterns) == max || i >= whole && len(patterns) != 0 {
+			break
+		}
+		if s.matchesAll(fragment, instances) && s.discriminant(fragment) {
+			if pattern, ok := literalPattern(fragment); ok && !s.overlaps(pattern) {
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	if len(patterns) != 0 || max == 0 {
+		return patterns, nil
+	}
+
+	if pattern, ok := s.alternation(instances); ok {
+		return []string{pattern}, nil
+	}
+	return nil, ErrNoPatternFound
+}
+
+// suggester checks fragments of instances against browsers and crawlers.
+type suggester struct {
+	matcher  *Matcher
+	browsers []string
+
+	// Case-insensitive patterns of the crawlers, compiled by overlaps.
+	patterns []*regexp.Regexp
+}
+
+var suggestURLRe = regexp.MustCompile(`https?://[^\s;)]+`)
+
+// fragments returns the fragments of the instance which may be suggested, in
+// the order of preference, and the number of fragments of whole words, which
+// come first.
+func (s *suggester) fragments(instance string) ([]string, int) {
+	type fragment struct {
+		text        string
+		start       int
+		endBoundary bool
+	}
+	inURL := make([]bool, len(instance))
+	for _, span := range suggestURLRe.FindAllStringIndex(instance, -1) {
+		for i := span[0]; i < span[1]; i++ {
+			inURL[i] = true
+		}
+	}
+
+	var fragments []fragment
+	seen := make(map[string]bool)
+	for start := 0; start < len(instance); start++ {
+		if start > 0 && isWordByte(instance[start-1]) || !isLetter(instance[start]) || inURL[start] {
+			continue
+		}
+		for end := start + minSuggestedLiteralLen; end <= len(instance); end++ {
+			text := instance[start:end]
+			if seen[text] {
+				continue
+			}
+			seen[text] = true
+			fragments = append(fragments, fragment{
+				text:        text,
+				start:       start,
+				endBoundary: isWordByte(text[len(text)-1]) && (end == len(instance) || !isWordByte(instance[end])),
+			})
+		}
+	}
+
+	sort.SliceStable(fragments, func(i, j int) bool {
+		a, b := fragments[i], fragments[j]
+		if a.endBoundary != b.endBoundary {
+			return a.endBoundary
+		}
+		if len(a.text) != len(b.text) {
+			return len(a.text) < len(b.text)
+		}
+		return a.start < b.start
+	})
+	texts := make([]string, len(fragments))
+	whole := 0
+	for i, f := range fragments {
+		texts[i] = f.text
+		if f.endBoundary {
+			whole++
+		}
+	}
+	return texts, whole
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isWordByte(c byte) bool {
+	return isLetter(c) || '0' <= c && c <= '9' || c >= 0x80
+}
+
+func (s *suggester) matchesAll(fragment string, instances []string) bool {
+	for _, instance := range instances {
+		if !strings.Contains(instance, fragment) {
+			return false
+		}
+	}
+	return true
+}
+
+// discriminant reports if the fragment is found in no browser User Agent and
+// no instance of the crawlers of the Matcher.
+func (s *suggester) discriminant(fragment string) bool {
+	for _, browser := range s.browsers {
+		if strings.Contains(browser, fragment) {
+			return false
+		}
+	}
+	for _, crawler := range s.matcher.crawlers {
+		for _, instance := range crawler.Instances {
+			if strings.Contains(instance, fragment) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// overlaps reports if the pattern is, ignoring case, the pattern of a crawler
+// of the Matcher, matches one or is matched by one.
+func (s *suggester) overlaps(pattern string) bool {
+	if s.patterns == nil {
+		s.patterns = make([]*regexp.Regexp, 0, len(s.matcher.crawlers))
+		for _, crawler := range s.matcher.crawlers {
+			if re, err := regexp.Compile("(?i)" + crawler.Pattern); err == nil {
+				s.patterns = append(s.patterns, re)
+			}
+		}
+	}
+
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return true
+	}
+	for _, crawler := range s.matcher.crawlers {
+		if strings.EqualFold(crawler.Pattern, pattern) || re.MatchString(crawler.Pattern) {
+			return true
+		}
+	}
+	for _, other := range s.patterns {
+		if other.MatchString(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// quotePattern returns the pattern matching s literally. Unlike
+// regexp.QuoteMeta, it escapes "/", as the patterns of crawler-user-agents.json
+// must.
+func quotePattern(s string) string {
+	return strings.ReplaceAll(regexp.QuoteMeta(s), "/", `\/`)
+}
+
+// literalPattern returns the pattern matching the fragment, if analyzePattern
+// expands it to literals only.
+func literalPattern(fragment string) (string, bool) {
+	pattern := quotePattern(fragment)
+	_, re, err := analyzePattern(pattern)
+	return pattern, err == nil && re == nil
+}
+
+// alternation covers the instances with discriminant fragments, choosing
+// greedily the fragment matching the most uncovered instances, the preferred
+// one among equals, and returns their alternation.
+func (s *suggester) alternation(instances []string) (string, bool) {
+	uncovered := append([]string(nil), instances...)
+	var alternatives []string
+	for len(uncovered) != 0 {
+		best, bestCount := "", 0
+		fragments, _ := s.fragments(uncovered[0])
+		for _, fragment := range fragments {
+			count := 0
+			for _, instance := range uncovered {
+				if strings.Contains(instance, fragment) {
+					count++
+				}
+			}
+			if count > bestCount && s.discriminant(fragment) && !s.overlaps(quotePattern(fragment)) {
+				best, bestCount = fragment, count
+			}
+		}
+		if bestCount == 0 {
+			return "", false
+		}
+		alternatives = append(alternatives, quotePattern(best))
+
+		remaining := uncovered[:0]
+		for _, instance := range uncovered {
+			if !strings.Contains(instance, best) {
+				remaining = append(remaining, instance)
+			}
+		}
+		uncovered = remaining
+	}
+
+	pattern := strings.Join(alternatives, "|")
+	_, re, err := analyzePattern(pattern)
+	return pattern, err == nil && re == nil && !s.overlaps(pattern)
+}
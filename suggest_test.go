@@ -0,0 +1,136 @@
+package agents
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// unescapedRe matches an unescaped "/" or "." in a pattern, as crawler-db
+// refuses.
+var unescapedRe = regexp.MustCompile(`(^|[^\\])[/.]`)
+
+func TestBrowserUserAgents(t *testing.T) {
+	if len(BrowserUserAgents) == 0 {
+		t.Fatal("no browser User Agents")
+	}
+	for _, userAgent := range BrowserUserAgents {
+		if indices := MatchingCrawlers(userAgent); len(indices) != 0 {
+			t.Errorf("Browser User Agent %q matches with crawlers %v.", userAgent, indices)
+		}
+	}
+}
+
+func TestSuggestPatterns(t *testing.T) {
+	cases := []struct {
+		name      string
+		instances []string
+		want      []string
+	}{
+		{
+			name: "product token",
+			instances: []string{
+				"Mozilla/5.0 (compatible; ExampleBot/1.2; +https://example.com/bot)",
+				"ExampleBot/1.3 (+https://example.com/bot)",
+			},
+			want: []string{"ExampleBot", `ExampleBot\/1`},
+		},
+		{
+			name: "token in browser user agent",
+			instances: []string{
+				"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko; Acme Fetch) Chrome/120.0.0.0 Safari/537.36",
+			},
+			want: []string{"Acme", "Acme Fetch", "Gecko; Acme"},
+		},
+		{
+			name: "alternation",
+			instances: []string{
+				"ZetaReader/1.0",
+				"Mozilla/5.0 (compatible; QuuxAgent/2.0)",
+			},
+			want: []string{"ZetaReader|QuuxAgent"},
+		},
+		{
+			name: "escaped slash and dot",
+			instances: []string{
+				"qq/1.2 (+https://example.com/qq)",
+			},
+			want: []string{`qq\/1`, `qq\/1\.2`, `qq\/1\.2 \(\+https`},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := SuggestPatterns(tc.instances, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("SuggestPatterns = %q, want %q", got, tc.want)
+			}
+			for _, pattern := range got {
+				if unescapedRe.MatchString(pattern) {
+					t.Errorf("pattern %q has an unescaped / or .", pattern)
+				}
+			}
+
+			m, err := NewMatcher([]Crawler{{Pattern: got[0], Instances: tc.instances}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, instance := range tc.instances {
+				if !m.IsCrawler(instance) {
+					t.Errorf("pattern %q does not match %q", got[0], instance)
+				}
+			}
+			for _, browser := range BrowserUserAgents {
+				if m.IsCrawler(browser) {
+					t.Errorf("pattern %q matches browser %q", got[0], browser)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestPatternsErrors(t *testing.T) {
+	if _, err := SuggestPatterns(nil, 1); err == nil {
+		t.Error("no instances is accepted")
+	}
+	if _, err := SuggestPatterns([]string{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"}, 1); err == nil {
+		t.Error("instance of a known crawler is accepted")
+	}
+	browser := BrowserUserAgents[0]
+	m, err := NewMatcher(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SuggestPatterns([]string{browser}, BrowserUserAgents, 1); !errors.Is(err, ErrNoPatternFound) {
+		t.Errorf("got error %v for a browser instance, want ErrNoPatternFound", err)
+	}
+}
+
+// TestSuggestPatternsOverlap checks that suggested patterns neither match
+// existing patterns nor are matched by them, ignoring case, as crawler-db
+// checks.
+func TestSuggestPatternsOverlap(t *testing.T) {
+	m, err := NewMatcher([]Crawler{
+		{Pattern: "examplebot", Instances: []string{"examplebot/1.0"}},
+		{Pattern: "Zeta[0-9]", Instances: []string{"Zeta7"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	instances := []string{"ExampleBot/2.0 (ZetaReader; Quux)"}
+	if m.IsCrawler(instances[0]) {
+		t.Fatalf("%q is matched by a test crawler", instances[0])
+	}
+
+	got, err := m.SuggestPatterns(instances, nil, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"Quux", "ZetaReader", "ZetaReader; Quux"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SuggestPatterns = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tag classifies crawlers. Valid tags are the Tag constants, which are also
+// the tags accepted in crawler-user-agents.json.
+type Tag string
+
+// Tags of crawlers.
+const (
+	// Crawlers of search engines.
+	TagSearchEngine Tag = "search-engine"
+
+	// Crawlers collecting training data or fetching pages for AI assistants.
+	TagAICrawler Tag = "ai-crawler"
+
+	// Fetchers of link previews of social networks and messaging apps.
+	TagSocialPreview Tag = "social-preview"
+
+	// Crawlers of SEO and backlink tools.
+	TagSEO Tag = "seo"
+
+	// Uptime and performance monitoring.
+	TagMonitoring Tag = "monitoring"
+
+	// RSS and Atom feed readers.
+	TagFeedReader Tag = "feed-reader"
+
+	// Web archives.
+	TagArchiver Tag = "archiver"
+
+	// Ad networks and ad verification.
+	TagAdvertising Tag = "advertising"
+
+	// Security and vulnerability scanners.
+	TagScanner Tag = "scanner"
+
+	// HTTP client libraries and command line tools.
+	TagHTTPLibrary Tag = "http-library"
+
+	// Headless browsers and automation frameworks.
+	TagBrowserAutomation Tag = "browser-automation"
+
+	// Research crawlers.
+	TagAcademic Tag = "academic"
+)
+
+// AllTags lists the valid tags. The position of a tag is its bit in TagSet.
+var AllTags = []Tag{
+	TagSearchEngine,
+	TagAICrawler,
+	TagSocialPreview,
+	TagSEO,
+	TagMonitoring,
+	TagFeedReader,
+	TagArchiver,
+	TagAdvertising,
+	TagScanner,
+	TagHTTPLibrary,
+	TagBrowserAutomation,
+	TagAcademic,
+}
+
+// bit returns the bit of the tag in TagSet, or false if the tag is not valid.
+func (t Tag) bit() (TagSet, bool) {
+	for i, tag := range AllTags {
+		if tag == t {
+			return 1 << i, true
+		}
+	}
+	return 0, false
+}
+
+// Valid reports if the tag is one of the Tag constants.
+func (t Tag) Valid() bool {
+	_, ok := t.bit()
+	return ok
+}
+
+// ParseTag returns the tag named s, or an error if it is not a valid tag.
+func ParseTag(s string) (Tag, error) {
+	tag := Tag(s)
+	if !tag.Valid() {
+		names := make([]string, len(AllTags))
+		for i, t := range AllTags {
+			names[i] = string(t)
+		}
+		return "", fmt.Errorf("unknown tag %q, accepted tags: %s", s, strings.Join(names, ", "))
+	}
+	return tag, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, rejecting unknown tags.
+// It is also used by encoding/json, including for map keys.
+func (t *Tag) UnmarshalText(text []byte) error {
+	tag, err := ParseTag(string(text))
+	if err != nil {
+		return err
+	}
+	*t = tag
+	return nil
+}
+
+// TagSet is a set of tags as a bitset, for fast membership checks.
+type TagSet uint16
+
+// NewTagSet returns the set of the tags. Invalid tags are ignored.
+func NewTagSet(tags ...Tag) TagSet {
+	var s TagSet
+	for _, tag := range tags {
+		if bit, ok := tag.bit(); ok {
+			s |= bit
+		}
+	}
+	return s
+}
+
+// Has reports if the tag is in the set.
+func (s TagSet) Has(tag Tag) bool {
+	bit, ok := tag.bit()
+	return ok && s&bit != 0
+}
+
+// HasAny reports if the sets have a tag in common.
+func (s TagSet) HasAny(other TagSet) bool {
+	return s&other != 0
+}
+
+// Tags returns the tags of the set, in the order of AllTags.
+func (s TagSet) Tags() []Tag {
+	var tags []Tag
+	for i, tag := range AllTags {
+		if s&(1<<i) != 0 {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// String returns the tags of the set separated by commas.
+func (s TagSet) String() string {
+	names := make([]string, 0, len(AllTags))
+	for _, tag := range s.Tags() {
+		names = append(names, string(tag))
+	}
+	return strings.Join(names, ",")
+}
+
+// MatchingTags returns the union of the tags of all crawlers matching the
+// User Agent.
+func MatchingTags(userAgent string) TagSet {
+	return defaultMatcher.MatchingTags(userAgent)
+}
+
+// MatchingTags returns the union of the tags of all crawlers matching the
+// User Agent.
+func (m *Matcher) MatchingTags(userAgent string) TagSet {
+	var s TagSet
+	for _, index := range m.MatchingCrawlers(userAgent) {
+		s |= m.tags[index]
+	}
+	return s
+}
+
+// CrawlerTags returns the tags of the crawler with the index as a TagSet.
+func (m *Matcher) CrawlerTags(index int) TagSet {
+	return m.tags[index]
+}
+
+// CrawlersWithTags returns the indices of the crawlers having at least one of
+// the tags.
+func (m *Matcher) CrawlersWithTags(tags TagSet) []int {
+	var indices []int
+	for i, s := range m.tags {
+		if s.HasAny(tags) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
@@ -0,0 +1,104 @@
+package agents
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestAllTags checks that AllTags and ACCEPTED_TAGS of validate.py agree.
+func TestAllTags(t *testing.T) {
+	data, err := os.ReadFile("validate.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := regexp.MustCompile(`(?s)ACCEPTED_TAGS = \{(.*?)\}`).FindSubmatch(data)
+	if block == nil {
+		t.Fatalf("ACCEPTED_TAGS not found in validate.py")
+	}
+	var accepted []string
+	for _, m := range regexp.MustCompile(`"([^"]+)"`).FindAllSubmatch(block[1], -1) {
+		accepted = append(accepted, string(m[1]))
+	}
+	sort.Strings(accepted)
+
+	var tags []string
+	for _, tag := range AllTags {
+		tags = append(tags, string(tag))
+	}
+	sort.Strings(tags)
+
+	if !reflect.DeepEqual(tags, accepted) {
+		t.Errorf("AllTags %v, ACCEPTED_TAGS of validate.py %v", tags, accepted)
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	if tag, err := ParseTag("ai-crawler"); err != nil || tag != TagAICrawler {
+		t.Errorf("ParseTag(ai-crawler) = %q, %v", tag, err)
+	}
+	if _, err := ParseTag("AI-Crawler"); err == nil {
+		t.Errorf("expected an error for a tag in another case")
+	}
+
+	_, err := LoadCrawlers(strings.NewReader(`[{"pattern": "examplebot", "tags": ["seo", "robot"]}]`))
+	if err == nil {
+		t.Fatalf("expected an error for an unknown tag")
+	}
+	for _, want := range []string{"examplebot", `"robot"`, "search-engine"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+
+	var config RateLimitConfig
+	if err := json.Unmarshal([]byte(`{"tags": {"robot": {"rate": 1, "burst": 1}}}`), &config); err == nil {
+		t.Errorf("expected an error for an unknown tag in a map key")
+	}
+}
+
+func TestTagSet(t *testing.T) {
+	s := NewTagSet(TagSEO, TagAICrawler, Tag("unknown"), TagSEO)
+	if !s.Has(TagSEO) || !s.Has(TagAICrawler) || s.Has(TagSearchEngine) || s.Has(Tag("unknown")) {
+		t.Errorf("unexpected membership in %v", s)
+	}
+	if got, want := s.Tags(), []Tag{TagAICrawler, TagSEO}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+	if got := s.String(); got != "ai-crawler,seo" {
+		t.Errorf("String() = %q", got)
+	}
+	if !s.HasAny(NewTagSet(TagSEO, TagScanner)) || s.HasAny(NewTagSet(TagScanner)) || s.HasAny(0) {
+		t.Errorf("unexpected HasAny results for %v", s)
+	}
+
+	all := NewTagSet(AllTags...)
+	if len(all.Tags()) != len(AllTags) {
+		t.Errorf("set of all tags has %d tags, want %d", len(all.Tags()), len(AllTags))
+	}
+}
+
+func TestMatchingTags(t *testing.T) {
+	for i, crawler := range Crawlers {
+		if got, want := defaultMatcher.CrawlerTags(i), NewTagSet(crawler.Tags...); got != want {
+			t.Errorf("CrawlerTags(%d) = %v, want %v", i, got, want)
+		}
+	}
+
+	if tags := MatchingTags(browserUA); tags != 0 {
+		t.Errorf("browser UA has tags %v", tags)
+	}
+	if tags := MatchingTags(gptbotUA); !tags.Has(TagAICrawler) {
+		t.Errorf("GPTBot has tags %v", tags)
+	}
+
+	for _, index := range defaultMatcher.CrawlersWithTags(NewTagSet(TagAcademic)) {
+		if !NewTagSet(Crawlers[index].Tags...).Has(TagAcademic) {
+			t.Errorf("crawler %q is not academic", Crawlers[index].Pattern)
+		}
+	}
+}
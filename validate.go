@@ -6,10 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/maphash"
+	"io"
 	"regexp"
 	"regexp/syntax"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 )
@@ -22,6 +24,13 @@ type Crawler struct {
 	// Regexp of User Agent of the crawler.
 	Pattern string `json:"pattern"`
 
+	// Regexp with one capture group extracting the version of the crawler
+	// from its User Agent (e.g. "2.1" of "Googlebot/2.1").
+	VersionPattern string `json:"version_pattern,omitempty"`
+
+	// Identifier of the organisation running the crawler, one of Operators.
+	Operator string `json:"operator,omitempty"`
+
 	// Discovery date.
 	AdditionDate time.Time `json:"addition_date"`
 
@@ -31,28 +40,65 @@ type Crawler struct {
 	// Examples of full User Agent strings.
 	Instances []string `json:"instances"`
 
-	// Classification tags (e.g. "search-engine", "ai-crawler", "seo").
-	Tags []string `json:"tags,omitempty"`
+	// Short description of the robot.
+	Description string `json:"description,omitempty"`
+
+	// Classification tags (e.g. TagSearchEngine, TagAICrawler, TagSEO).
+	Tags []Tag `json:"tags,omitempty"`
+
+	// Product tokens the crawler honours in robots.txt (e.g. "GPTBot").
+	RobotsTokens []string `json:"robots_tokens,omitempty"`
 }
 
 // Private type needed to convert addition_date from/to the format used in JSON.
 type jsonCrawler struct {
-	Pattern      string   `json:"pattern"`
-	AdditionDate string   `json:"addition_date"`
-	URL          string   `json:"url"`
-	Instances    []string `json:"instances"`
-	Tags         []string `json:"tags,omitempty"`
+	Pattern        string   `json:"pattern"`
+	VersionPattern string   `json:"version_pattern,omitempty"`
+	Operator       string   `json:"operator,omitempty"`
+	AdditionDate   string   `json:"addition_date,omitempty"`
+	URL            string   `json:"url"`
+	Instances      []string `json:"instances"`
+	Description    string   `json:"description,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	RobotsTokens   []string `json:"robots_tokens,omitempty"`
+}
+
+// CrawlerFields are the JSON fields of a crawler written by
+// Crawler.MarshalJSON, in their order.
+var CrawlerFields = []string{
+	"pattern", "version_pattern", "operator", "addition_date", "url", "instances", "description", "tags", "robots_tokens",
 }
 
 const timeLayout = "2006/01/02"
 
+func tagNames(tags []Tag) []string {
+	if tags == nil {
+		return nil
+	}
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = string(tag)
+	}
+	return names
+}
+
+// MarshalJSON encodes the crawler in the format of crawler-user-agents.json.
+// As in the file, addition_date is omitted if AdditionDate is zero, and so
+// are the other optional fields if they are empty; pattern, url and instances
+// are always written.
 func (c Crawler) MarshalJSON() ([]byte, error) {
 	jc := jsonCrawler{
-		Pattern:      c.Pattern,
-		AdditionDate: c.AdditionDate.Format(timeLayout),
-		URL:          c.URL,
-		Instances:    c.Instances,
-		Tags:         c.Tags,
+		Pattern:        c.Pattern,
+		VersionPattern: c.VersionPattern,
+		Operator:       c.Operator,
+		URL:            c.URL,
+		Instances:      c.Instances,
+		Description:    c.Description,
+		Tags:           tagNames(c.Tags),
+		RobotsTokens:   c.RobotsTokens,
+	}
+	if !c.AdditionDate.IsZero() {
+		jc.AdditionDate = c.AdditionDate.Format(timeLayout)
 	}
 	return json.Marshal(jc)
 }
@@ -64,14 +110,29 @@ func (c *Crawler) UnmarshalJSON(b []byte) error {
 	}
 
 	c.Pattern = jc.Pattern
+	c.VersionPattern = jc.VersionPattern
+	c.Operator = jc.Operator
 	c.URL = jc.URL
 	c.Instances = jc.Instances
-	c.Tags = jc.Tags
+	c.Description = jc.Description
+	c.RobotsTokens = jc.RobotsTokens
 
 	if c.Pattern == "" {
 		return fmt.Errorf("empty pattern in record %s", string(b))
 	}
 
+	c.Tags = make([]Tag, 0, len(jc.Tags))
+	for _, name := range jc.Tags {
+		tag, err := ParseTag(name)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %w", c.Pattern, err)
+		}
+		c.Tags = append(c.Tags, tag)
+	}
+	if len(c.Tags) == 0 {
+		c.Tags = nil
+	}
+
 	if jc.AdditionDate != "" {
 		tim, err := time.ParseInLocation(timeLayout, jc.AdditionDate, time.UTC)
 		if err != nil {
@@ -101,16 +162,35 @@ var Crawlers = func() []Crawler {
 // (pre-filter with this main literal before running a regexp). In the case such
 // a main literal can't be found or the regexp is invalid, an error is returned.
 func analyzePattern(pattern string) ([]string, *regexp.Regexp, error) {
+	return analyze(pattern, false)
+}
+
+// analyzePatternFolded is analyzePattern for case-insensitive matching. The
+// returned texts are in lower case and must be searched in the lower-cased
+// text, and the returned regexp is case-insensitive. Case-insensitive parts of
+// the pattern are not expanded by unwrapCase, so the list is not longer than
+// the one of analyzePattern.
+func analyzePatternFolded(pattern string) ([]string, *regexp.Regexp, error) {
+	return analyze(pattern, true)
+}
+
+func analyze(pattern string, fold bool) ([]string, *regexp.Regexp, error) {
 	re, err := syntax.Parse(pattern, syntax.Perl)
 	if err != nil {
 		return nil, nil, fmt.Errorf("re %q does not compile: %w", pattern, err)
 	}
 	re = re.Simplify()
+	if fold {
+		clearFoldCase(re)
+	}
 
 	// Try to convert it to the list of literals.
 	const maxLiterals = 100
 	literals, ok := literalizeRegexp(re, maxLiterals)
 	if ok {
+		if fold {
+			literals = lowerUnique(literals)
+		}
 		return literals, nil, nil
 	}
 
@@ -122,9 +202,36 @@ func analyzePattern(pattern string) ([]string, *regexp.Regexp, error) {
 		return nil, nil, fmt.Errorf("re %q does not contain sufficiently long literal to serve an indicator. The longest literal is %q", pattern, mainLiteral)
 	}
 
+	if fold {
+		return []string{strings.ToLower(mainLiteral)}, regexp.MustCompile("(?i)" + pattern), nil
+	}
 	return []string{mainLiteral}, regexp.MustCompile(pattern), nil
 }
 
+// clearFoldCase removes the case-insensitive flag from the regexp and its
+// sub-expressions. Character classes of case-insensitive parts already
+// contain both cases, and literals are lower-cased by the caller.
+func clearFoldCase(re *syntax.Regexp) {
+	re.Flags &^= syntax.FoldCase
+	for _, sub := range re.Sub {
+		clearFoldCase(sub)
+	}
+}
+
+// lowerUnique lower-cases the texts and removes duplicates.
+func lowerUnique(texts []string) []string {
+	seen := make(map[string]bool, len(texts))
+	results := make([]string, 0, len(texts))
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		if !seen[text] {
+			seen[text] = true
+			results = append(results, text)
+		}
+	}
+	return results
+}
+
 // literalizeRegexp expands a regexp to the list of matching sub-strings.
 // Iff a text matches the regexp, it contains at least one of the returned
 // texts. Argument maxLiterals regulates the maximum number of patterns to
@@ -384,9 +491,33 @@ type regexpPattern struct {
 	index int
 }
 
-type matcher struct {
+// Matcher finds crawlers matching User Agents. Package level functions
+// IsCrawler and MatchingCrawlers use a Matcher built from Crawlers; use
+// NewMatcher to match against another list, e.g. loaded with LoadCrawlers.
+type Matcher struct {
+	crawlers []Crawler
 	replacer *strings.Replacer
 	regexps  []regexpPattern
+
+	// Compiled version patterns, nil for crawlers without one.
+	versions []*regexp.Regexp
+
+	// Tags of crawlers.
+	tags []TagSet
+
+	// Length of the shortest literal searched for each crawler, a measure of
+	// the specificity of its pattern used by Scorer.
+	literalLens []int
+
+	// Whether User Agents are lower-cased before searching literals.
+	caseInsensitive bool
+
+	// Normalizer of User Agents, may be nil.
+	normalize func(string) string
+
+	// Compiled patterns of all crawlers, used by Classify.
+	patternsOnce sync.Once
+	patterns     []*regexp.Regexp
 }
 
 var uniqueToken = hex.EncodeToString((&maphash.Hash{}).Sum(nil))
@@ -398,22 +529,65 @@ const (
 	regexpLabel    = '*'
 )
 
-var m = func() matcher {
+// MatcherOptions configures a Matcher built by NewMatcherWithOptions.
+type MatcherOptions struct {
+	// Match patterns ignoring case, e.g. for User Agents lower-cased by a CDN
+	// or a log normaliser: "googlebot/2.1" then matches "Googlebot\/".
+	CaseInsensitive bool
+
+	// Function applied to User Agents before matching, e.g.
+	// NormalizeUserAgent. Results of Classify contain the normalized
+	// User Agent.
+	Normalize func(userAgent string) string
+}
+
+// NewMatcher builds a Matcher for the list of crawlers. It returns an error if
+// a pattern does not compile or can't be searched efficiently, or if a version
+// pattern does not compile or does not have exactly one capture group.
+func NewMatcher(crawlers []Crawler) (*Matcher, error) {
+	return NewMatcherWithOptions(crawlers, MatcherOptions{})
+}
+
+// NewMatcherWithOptions builds a Matcher for the list of crawlers with the
+// options. See NewMatcher.
+func NewMatcherWithOptions(crawlers []Crawler, options MatcherOptions) (*Matcher, error) {
 	if len(uniqueToken) != uniqueTokenLen {
 		panic("len(uniqueToken) != uniqueTokenLen")
 	}
 
+	if len(crawlers) >= 100000 {
+		return nil, fmt.Errorf("too many crawlers: %d", len(crawlers))
+	}
+
 	regexps := []regexpPattern{}
-	oldnew := make([]string, 0, len(Crawlers)*2)
+	oldnew := make([]string, 0, len(crawlers)*2)
 
 	// Put re-based patterns to the end to prevent AdsBot-Google from
 	// shadowing AdsBot-Google-Mobile.
 	var oldnew2 []string
 
-	for i, crawler := range Crawlers {
-		literals, re, err := analyzePattern(crawler.Pattern)
+	versions := make([]*regexp.Regexp, len(crawlers))
+	tags := make([]TagSet, len(crawlers))
+	literalLens := make([]int, len(crawlers))
+
+	for i, crawler := range crawlers {
+		analyzer := analyzePattern
+		if options.CaseInsensitive {
+			analyzer = analyzePatternFolded
+		}
+		literals, re, err := analyzer(crawler.Pattern)
 		if err != nil {
-			panic(err)
+			return nil, err
+		}
+
+		tags[i] = NewTagSet(crawler.Tags...)
+		literalLens[i] = shortestLiteralLen(literals)
+
+		if crawler.VersionPattern != "" {
+			versions[i], err = compileVersionPattern(crawler.VersionPattern, options.CaseInsensitive)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		label := literalLabel
@@ -446,18 +620,100 @@ var m = func() matcher {
 	r := strings.NewReplacer(oldnew...)
 	r.Replace("") // To cause internal build process.
 
-	return matcher{
+	return &Matcher{
+		crawlers: crawlers,
 		replacer: r,
 		regexps:  regexps2,
+		versions: versions,
+		tags:     tags,
+
+		literalLens:     literalLens,
+		caseInsensitive: options.CaseInsensitive,
+		normalize:       options.Normalize,
+	}, nil
+}
+
+// compileVersionPattern compiles a version pattern, checking that it has
+// exactly one capture group.
+func compileVersionPattern(pattern string, caseInsensitive bool) (*regexp.Regexp, error) {
+	expr := pattern
+	if caseInsensitive {
+		expr = "(?i)" + pattern
 	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("version pattern %q does not compile: %w", pattern, err)
+	}
+	if re.NumSubexp() != 1 {
+		return nil, fmt.Errorf("version pattern %q has %d capture groups, want 1", pattern, re.NumSubexp())
+	}
+	return re, nil
+}
+
+var defaultMatcher = func() *Matcher {
+	m, err := NewMatcher(Crawlers)
+	if err != nil {
+		panic(err)
+	}
+	return m
 }()
 
+// DefaultMatcher returns the Matcher built from Crawlers, which is used by
+// package level functions.
+func DefaultMatcher() *Matcher {
+	return defaultMatcher
+}
+
+// LoadCrawlers reads a list of crawlers in the format of
+// crawler-user-agents.json.
+func LoadCrawlers(r io.Reader) ([]Crawler, error) {
+	var crawlers []Crawler
+	if err := json.NewDecoder(r).Decode(&crawlers); err != nil {
+		return nil, err
+	}
+	return crawlers, nil
+}
+
+// Crawlers returns the list of crawlers the Matcher was built from. Indices
+// returned by MatchingCrawlers refer to this list.
+func (m *Matcher) Crawlers() []Crawler {
+	return m.crawlers
+}
+
 // Returns if User Agent string matches any of crawler patterns.
 func IsCrawler(userAgent string) bool {
+	return defaultMatcher.IsCrawler(userAgent)
+}
+
+// Finds all crawlers matching the User Agent and returns the list of their indices in Crawlers.
+func MatchingCrawlers(userAgent string) []int {
+	return defaultMatcher.MatchingCrawlers(userAgent)
+}
+
+// normalized applies the normalizer of the Matcher, if any.
+func (m *Matcher) normalized(userAgent string) string {
+	if m.normalize == nil {
+		return userAgent
+	}
+	return m.normalize(userAgent)
+}
+
+// searchText returns the text in which the replacer searches literals of the
+// User Agent.
+func (m *Matcher) searchText(userAgent string) string {
+	if m.caseInsensitive {
+		userAgent = strings.ToLower(userAgent)
+	}
+	return "^" + userAgent + "$"
+}
+
+// IsCrawler returns if User Agent string matches any of crawler patterns.
+func (m *Matcher) IsCrawler(userAgent string) bool {
 	// This code is mostly copy-paste of MatchingCrawlers,
 	// but with early exit logic, so it works a but faster.
 
-	text := "^" + userAgent + "$"
+	userAgent = m.normalized(userAgent)
+	text := m.searchText(userAgent)
 	replaced := m.replacer.Replace(text)
 	if replaced == text {
 		return false
@@ -499,9 +755,15 @@ func IsCrawler(userAgent string) bool {
 	return false
 }
 
-// Finds all crawlers matching the User Agent and returns the list of their indices in Crawlers.
-func MatchingCrawlers(userAgent string) []int {
-	text := "^" + userAgent + "$"
+// MatchingCrawlers finds all crawlers matching the User Agent and returns the
+// list of their indices in the list of crawlers of the Matcher.
+func (m *Matcher) MatchingCrawlers(userAgent string) []int {
+	return m.matchingCrawlers(m.normalized(userAgent))
+}
+
+// matchingCrawlers is MatchingCrawlers for a normalized User Agent.
+func (m *Matcher) matchingCrawlers(userAgent string) []int {
+	text := m.searchText(userAgent)
 	replaced := m.replacer.Replace(text)
 	if replaced == text {
 		return []int{}
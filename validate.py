@@ -37,6 +37,12 @@ JSON_SCHEMA = {
             "description": {"type": "string"}, # optional
             "addition_date": {"type": "string"}, # optional
             "depends_on": {"type": "array"}, # allows an instance to match twice
+            "version_pattern": {"type": "string"}, # optional, regexp capturing the version in one group
+            "operator": {"type": "string"}, # optional, id of the organisation in operators.json
+            "robots_tokens": { # optional, robots.txt product tokens honoured by the crawler
+                "type": "array",
+                "items": {"type": "string", "pattern": "^[A-Za-z0-9_.-]+$"},
+            },
             "tags": { # optional, array of classification tags
                 "type": "array",
                 "items": {"type": "string", "enum": sorted(ACCEPTED_TAGS)},
@@ -51,6 +57,11 @@ def main():
     with open('crawler-user-agents.json') as f:
         json_data = json.load(f)
 
+    with open('operators.json') as f:
+        operator_ids = [operator['id'] for operator in json.load(f)]
+    if len(operator_ids) != len(set(operator_ids)):
+        raise ValueError('operators.json has duplicate ids')
+
     # check format using JSON Schema
     validate(json_data, JSON_SCHEMA)
 
@@ -94,6 +105,11 @@ def main():
                     )
                 )
 
+        # check that the operator, if present, is in operators.json
+        if 'operator' in entry and entry['operator'] not in operator_ids:
+            raise ValueError('Pattern {!r} has unknown operator {!r}. Add it to operators.json'
+                             .format(pattern, entry['operator']))
+
         # assert that field "addition_date" has format "2019/12/23",
         if 'addition_date' in entry:
             if not re.match(r'\d{4}/\d{2}/\d{2}', entry['addition_date']):
@@ -101,6 +117,17 @@ def main():
             # parse the date with datetime
             datetime.datetime.strptime(entry['addition_date'], '%Y/%m/%d')
             
+        # check that version_pattern has one capture group and finds the version of every instance
+        if 'version_pattern' in entry:
+            version_re = re.compile(entry['version_pattern'])
+            if version_re.groups != 1:
+                raise ValueError('version_pattern {!r} of {!r} must have exactly one capture group'
+                                 .format(entry['version_pattern'], pattern))
+            for instance in entry['instances']:
+                if not version_re.search(instance):
+                    raise ValueError('version_pattern {!r} misses instance {!r}'
+                                     .format(entry['version_pattern'], instance))
+
         # canonicalize entry
         if 'depends_on' not in entry: entry['depends_on'] = []
             
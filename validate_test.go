@@ -1,6 +1,7 @@
 package agents
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestAnalyzePattern tests analyzePattern function on many cases, including
@@ -474,25 +476,10 @@ func contains(list []int, value int) bool {
 	return false
 }
 
-var acceptedTags = map[string]bool{
-	"search-engine":    true,
-	"ai-crawler":       true,
-	"social-preview":   true,
-	"seo":              true,
-	"monitoring":       true,
-	"feed-reader":      true,
-	"archiver":         true,
-	"advertising":      true,
-	"scanner":          true,
-	"http-library":     true,
-	"browser-automation": true,
-	"academic":         true,
-}
-
 func TestTags(t *testing.T) {
 	for _, crawler := range Crawlers {
 		for _, tag := range crawler.Tags {
-			if !acceptedTags[tag] {
+			if !tag.Valid() {
 				t.Errorf("Pattern %q has unknown tag %q.", crawler.Pattern, tag)
 			}
 		}
@@ -602,3 +589,207 @@ func BenchmarkMatchingCrawlersNegative(b *testing.B) {
 		}
 	}
 }
+
+func TestNewMatcher(t *testing.T) {
+	crawlers, err := LoadCrawlers(strings.NewReader(`[
+		{"pattern": "examplebot", "instances": ["examplebot/1.0"]},
+		{"pattern": "other[0-9]+bot", "instances": ["other42bot"]}
+	]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	matcher, err := NewMatcher(crawlers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !matcher.IsCrawler("Mozilla/5.0 examplebot/1.0") {
+		t.Errorf("examplebot is not detected as a crawler")
+	}
+	if got := matcher.MatchingCrawlers("other42bot"); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("MatchingCrawlers(other42bot) = %v, want [1]", got)
+	}
+	if matcher.IsCrawler(crawlerUA) {
+		t.Errorf("crawler UA %q matches a list without its crawler", crawlerUA)
+	}
+
+	if _, err := NewMatcher([]Crawler{{Pattern: "broken re["}}); err == nil {
+		t.Errorf("expected an error for a broken pattern")
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	matcher, err := NewMatcherWithOptions(Crawlers, MatcherOptions{CaseInsensitive: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, crawler := range Crawlers {
+		for _, instance := range crawler.Instances {
+			for _, userAgent := range []string{instance, strings.ToLower(instance), strings.ToUpper(instance)} {
+				if !matcher.IsCrawler(userAgent) {
+					t.Errorf("%q is not a crawler", userAgent)
+				}
+				if !contains(matcher.MatchingCrawlers(userAgent), i) {
+					t.Errorf("pattern %q does not match %q", crawler.Pattern, userAgent)
+				}
+			}
+		}
+	}
+
+	if matcher.IsCrawler(strings.ToLower(browserUA)) {
+		t.Errorf("lower-cased browser UA %q is a crawler", browserUA)
+	}
+
+	result := matcher.Classify("mozilla/5.0 (compatible; googlebot/2.1; +http://www.google.com/bot.html)")
+	if !result.IsCrawler() || result.Matches[0].Version != "2.1" {
+		t.Errorf("unexpected classification of lower-cased Googlebot: %+v", result.Matches)
+	} else if span := result.UserAgent[result.Matches[0].Start:result.Matches[0].End]; span != "googlebot/" {
+		t.Errorf("matched %q", span)
+	}
+
+	if defaultMatcher.IsCrawler("mozilla/5.0 (compatible; googlebot/2.1; +http://www.google.com/bot.html)") {
+		t.Errorf("the default matcher is case-insensitive")
+	}
+}
+
+// TestAnalyzePatternFolded checks that case-insensitive analysis does not
+// produce more literals than case-sensitive one, even for patterns with
+// case-insensitive parts which unwrapCase expands.
+func TestAnalyzePatternFolded(t *testing.T) {
+	cases := []struct {
+		input        string
+		wantPatterns []string
+		wantRegexp   bool
+	}{
+		{input: "Googlebot", wantPatterns: []string{"googlebot"}},
+		{input: "(?i)bot", wantPatterns: []string{"bot"}},
+		{input: "[wW]get", wantPatterns: []string{"wget"}},
+		{input: "(?i)(crawler|spider)", wantPatterns: []string{"crawler", "spider"}},
+		{input: "^curl", wantPatterns: []string{"^curl"}},
+		{input: "Ahrefs(Bot|SiteAudit)", wantPatterns: []string{"ahrefsbot", "ahrefssiteaudit"}},
+		{input: "Bot[0-9]+X", wantPatterns: []string{"bot"}, wantRegexp: true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			patterns, re, err := analyzePatternFolded(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(patterns, tc.wantPatterns) {
+				t.Errorf("got patterns %q, want %q", patterns, tc.wantPatterns)
+			}
+			if (re != nil) != tc.wantRegexp {
+				t.Errorf("got regexp %v", re)
+			}
+			if re != nil && !re.MatchString("bot42x") {
+				t.Errorf("regexp %v is case-sensitive", re)
+			}
+		})
+	}
+
+	total, totalFolded := 0, 0
+	for _, crawler := range Crawlers {
+		patterns, _, err := analyzePattern(crawler.Pattern)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		folded, _, err := analyzePatternFolded(crawler.Pattern)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(folded) > len(patterns) {
+			t.Errorf("pattern %q has %d folded literals, more than %d", crawler.Pattern, len(folded), len(patterns))
+		}
+		total += len(patterns)
+		totalFolded += len(folded)
+	}
+	t.Logf("%d literals, %d folded literals", total, totalFolded)
+}
+
+func TestCrawlerMarshalJSON(t *testing.T) {
+	cases := []struct {
+		name    string
+		crawler Crawler
+		want    string
+	}{
+		{
+			"minimal",
+			Crawler{Pattern: "examplebot"},
+			`{"pattern":"examplebot","url":"","instances":null}`,
+		},
+		{
+			"full",
+			Crawler{
+				Pattern:        "examplebot",
+				VersionPattern: `examplebot/(\d+)`,
+				Operator:       "example",
+				AdditionDate:   time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				URL:            "https://example.com",
+				Instances:      []string{"examplebot/1"},
+				Description:    "Example",
+				Tags:           []Tag{TagSEO},
+				RobotsTokens:   []string{"examplebot"},
+			},
+			`{"pattern":"examplebot","version_pattern":"examplebot/(\\d+)","operator":"example","addition_date":"2020/01/02","url":"https://example.com","instances":["examplebot/1"],"description":"Example","tags":["seo"],"robots_tokens":["examplebot"]}`,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.crawler)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != tc.want {
+				t.Errorf("got %s, want %s", data, tc.want)
+			}
+			var got Crawler
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.crawler) {
+				t.Errorf("round trip: got %+v, want %+v", got, tc.crawler)
+			}
+		})
+	}
+}
+
+func TestCrawlerFields(t *testing.T) {
+	crawler := Crawler{
+		Pattern:        "examplebot",
+		VersionPattern: `examplebot/(\d+)`,
+		Operator:       "example",
+		AdditionDate:   time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		URL:            "https://example.com",
+		Instances:      []string{"examplebot/1"},
+		Description:    "Example",
+		Tags:           []Tag{TagSEO},
+		RobotsTokens:   []string{"examplebot"},
+	}
+	data, err := json.Marshal(crawler)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Keys of the object, in order.
+	var keys []string
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.Token()
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		keys = append(keys, key.(string))
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(keys, CrawlerFields) {
+		t.Errorf("MarshalJSON writes %q, CrawlerFields is %q", keys, CrawlerFields)
+	}
+}